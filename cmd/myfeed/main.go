@@ -1,10 +1,16 @@
 package main
 
 import (
+	"fmt"
 	"os"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/joho/godotenv"
 
+	"github.com/themisir/myfeed/pkg/auth"
+	"github.com/themisir/myfeed/pkg/oidc"
 	"github.com/themisir/myfeed/pkg/web"
 	"github.com/themisir/myfeed/static"
 )
@@ -27,6 +33,26 @@ func main() {
 			TemplateRoot: "views",
 			StaticFS:     static.FS,
 			DataSource:   dataSource,
+
+			MinRefreshInterval: durationEnv("MIN_REFRESH_INTERVAL"),
+			MaxRefreshInterval: durationEnv("MAX_REFRESH_INTERVAL"),
+
+			FetchWorkers:         intEnv("FETCH_WORKERS"),
+			FetchHostConcurrency: intEnv("FETCH_HOST_CONCURRENCY"),
+
+			RetentionDays:  intEnv("RETENTION_DAYS"),
+			RetentionPosts: intEnv("RETENTION_POSTS"),
+
+			AuthKeys:      keysEnv("AUTH_KEYS"),
+			AuthActiveKey: os.Getenv("AUTH_ACTIVE_KEY"),
+
+			OIDCProviders: providersEnv("OIDC_PROVIDERS"),
+
+			BaseUrl:   os.Getenv("BASE_URL"),
+			MailerUrl: os.Getenv("MAILER_URL"),
+			MailFrom:  os.Getenv("MAIL_FROM"),
+
+			TrustedProxies: listEnv("TRUSTED_PROXIES"),
 		}
 
 		app := web.NewApp(config)
@@ -35,3 +61,104 @@ func main() {
 		panic("DATABASE_URL environment variable is missing")
 	}
 }
+
+// durationEnv parses duration from the given environment variable, zero is
+// returned when the variable is missing
+func durationEnv(key string) time.Duration {
+	value, ok := os.LookupEnv(key)
+	if !ok {
+		return 0
+	}
+
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		panic(fmt.Sprintf("%s environment variable is not a valid duration: %s", key, err))
+	}
+	return d
+}
+
+// intEnv parses integer from the given environment variable, zero is
+// returned when the variable is missing
+func intEnv(key string) int {
+	value, ok := os.LookupEnv(key)
+	if !ok {
+		return 0
+	}
+
+	i, err := strconv.Atoi(value)
+	if err != nil {
+		panic(fmt.Sprintf("%s environment variable is not a valid integer: %s", key, err))
+	}
+	return i
+}
+
+// keysEnv parses auth keys from the given environment variable, nil is
+// returned when the variable is missing
+func keysEnv(key string) []auth.Key {
+	value, ok := os.LookupEnv(key)
+	if !ok {
+		return nil
+	}
+
+	keys, err := auth.ParseKeys(value)
+	if err != nil {
+		panic(fmt.Sprintf("%s environment variable is not valid: %s", key, err))
+	}
+	return keys
+}
+
+// providersEnv reads OpenID Connect providers listed in the given
+// environment variable. Each provider is configured using OIDC_<NAME>_*
+// variables.
+func providersEnv(key string) []oidc.Config {
+	var providers []oidc.Config
+	for _, name := range strings.Split(os.Getenv(key), ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+
+		prefix := "OIDC_" + strings.ToUpper(name) + "_"
+		config := oidc.Config{
+			Name:          name,
+			Title:         os.Getenv(prefix + "TITLE"),
+			Issuer:        os.Getenv(prefix + "ISSUER"),
+			ClientId:      os.Getenv(prefix + "CLIENT_ID"),
+			ClientSecret:  os.Getenv(prefix + "CLIENT_SECRET"),
+			Scopes:        strings.Fields(os.Getenv(prefix + "SCOPES")),
+			AutoProvision: boolEnv(prefix + "AUTO_PROVISION"),
+		}
+		if config.Issuer == "" || config.ClientId == "" {
+			panic(fmt.Sprintf("%sISSUER and %sCLIENT_ID environment variables are required", prefix, prefix))
+		}
+		providers = append(providers, config)
+	}
+	return providers
+}
+
+// listEnv returns items of the comma separated list in the given environment
+// variable, nil is returned when the variable is missing
+func listEnv(key string) []string {
+	var items []string
+	for _, item := range strings.Split(os.Getenv(key), ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// boolEnv parses boolean from the given environment variable, false is
+// returned when the variable is missing
+func boolEnv(key string) bool {
+	value, ok := os.LookupEnv(key)
+	if !ok {
+		return false
+	}
+
+	b, err := strconv.ParseBool(value)
+	if err != nil {
+		panic(fmt.Sprintf("%s environment variable is not a valid boolean: %s", key, err))
+	}
+	return b
+}
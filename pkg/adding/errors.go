@@ -0,0 +1,5 @@
+package adding
+
+import "errors"
+
+var ErrAlreadyExists = errors.New("entity already exists")
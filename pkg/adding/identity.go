@@ -0,0 +1,13 @@
+package adding
+
+type (
+	IdentityData struct {
+		Provider string
+		Subject  string
+		UserId   string
+		Email    string
+	}
+	IdentityRepository interface {
+		AddIdentity(data IdentityData) error
+	}
+)
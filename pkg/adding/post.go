@@ -5,9 +5,12 @@ import "time"
 type (
 	PostData struct {
 		SourceId    int
+		Guid        string
 		Title       string
 		Description string
 		Url         string
+		Author      string
+		Categories  []string
 		PublishedAt *time.Time
 		UpdatedAt   *time.Time
 	}
@@ -22,5 +25,8 @@ type (
 	PostRepository interface {
 		AddPost(data PostData) (Post, error)
 		AddManyPosts(items ...PostData) error
+		// UpsertPosts inserts new posts and updates existing ones matched
+		// by source id and guid
+		UpsertPosts(items ...PostData) error
 	}
 )
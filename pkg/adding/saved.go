@@ -0,0 +1,8 @@
+package adding
+
+type SavedItemRepository interface {
+	// SavePost copies the post into the saved items of the user, saving
+	// the same post again keeps the existing copy. listing.ErrNotFound is
+	// returned when the post isn't in a feed visible to the user.
+	SavePost(userId string, postId int) error
+}
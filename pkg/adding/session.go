@@ -0,0 +1,19 @@
+package adding
+
+import "time"
+
+type (
+	SessionData struct {
+		Id        string
+		UserId    string
+		IP        string
+		UserAgent string
+		CreatedAt time.Time
+		ExpiresAt time.Time
+	}
+	SessionRepository interface {
+		// AddSession records a new session removing expired sessions of
+		// the user
+		AddSession(data SessionData) error
+	}
+)
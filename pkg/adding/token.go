@@ -0,0 +1,14 @@
+package adding
+
+type (
+	TokenData struct {
+		UserId string
+		Name   string
+		// Hash is the hash of the token, tokens themselves aren't stored
+		Hash   string
+		Scopes []string
+	}
+	TokenRepository interface {
+		AddToken(data TokenData) error
+	}
+)
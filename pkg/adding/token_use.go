@@ -0,0 +1,10 @@
+package adding
+
+import "time"
+
+type UsedTokenRepository interface {
+	// AddUsedToken records use of a single-use token, ErrAlreadyExists is
+	// returned when the token is already used. Records are kept until the
+	// token expires.
+	AddUsedToken(id string, expiresAt time.Time) error
+}
@@ -5,12 +5,16 @@ type (
 		Email        string
 		Username     string
 		PasswordHash string
+		// EmailVerified is set when the email is verified by an identity
+		// provider
+		EmailVerified bool
 	}
 	User interface {
 		Id() string
 		Email() string
 		Username() string
 		PasswordHash() string
+		EmailVerified() bool
 	}
 	UserRepository interface {
 		AddUser(data UserData) (User, error)
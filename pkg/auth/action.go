@@ -0,0 +1,100 @@
+package auth
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"time"
+
+	"github.com/golang-jwt/jwt"
+	"github.com/google/uuid"
+	"github.com/themisir/myfeed/pkg/adding"
+	"github.com/themisir/myfeed/pkg/listing"
+)
+
+// Purposes of the action tokens sent to users by email
+const (
+	PurposeVerifyEmail   = "verify-email"
+	PurposeResetPassword = "reset-password"
+)
+
+var ErrInvalidToken = errors.New("token is invalid, expired or already used")
+
+// UsedTokenStore records redeemed action tokens
+type UsedTokenStore interface {
+	AddUsedToken(id string, expiresAt time.Time) error
+}
+
+// UserFinder loads users action tokens are issued for
+type UserFinder interface {
+	GetUserById(id string) (listing.User, error)
+}
+
+// ActionTokens issues signed, expiring and single-use tokens authorizing a
+// single action such as verifying email or resetting password. Tokens are
+// bound to the email and password of the user, so changing either of them
+// invalidates issued tokens.
+type ActionTokens struct {
+	keys  *KeySet
+	store UsedTokenStore
+}
+
+func NewActionTokens(keys *KeySet, store UsedTokenStore) *ActionTokens {
+	return &ActionTokens{keys, store}
+}
+
+type actionClaims struct {
+	jwt.StandardClaims
+	Email    string `json:"email"`
+	Password string `json:"pwd"`
+}
+
+// Issue creates token for the purpose valid for the given duration
+func (t *ActionTokens) Issue(purpose string, user listing.User, lifetime time.Duration) (string, error) {
+	now := time.Now()
+	return t.keys.Sign(&actionClaims{
+		StandardClaims: jwt.StandardClaims{
+			Id:        uuid.New().String(),
+			Audience:  purpose,
+			Subject:   user.Id(),
+			IssuedAt:  now.Unix(),
+			ExpiresAt: now.Add(lifetime).Unix(),
+		},
+		Email:    user.Email(),
+		Password: passwordFingerprint(user.PasswordHash()),
+	})
+}
+
+// Redeem validates token issued for the purpose and marks it used, user the
+// token is issued for is returned. ErrInvalidToken is returned when the
+// token can't be used.
+func (t *ActionTokens) Redeem(purpose string, token string, users UserFinder) (listing.User, error) {
+	claims := new(actionClaims)
+	parsed, err := jwt.ParseWithClaims(token, claims, t.keys.Keyfunc)
+	if err != nil || !parsed.Valid || !claims.VerifyAudience(purpose, true) {
+		return nil, ErrInvalidToken
+	}
+
+	user, err := users.GetUserById(claims.Subject)
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+	if user.Email() != claims.Email || passwordFingerprint(user.PasswordHash()) != claims.Password {
+		return nil, ErrInvalidToken
+	}
+
+	if err := t.store.AddUsedToken(claims.Id, time.Unix(claims.ExpiresAt, 0)); err != nil {
+		if err == adding.ErrAlreadyExists {
+			return nil, ErrInvalidToken
+		}
+		return nil, err
+	}
+	return user, nil
+}
+
+// passwordFingerprint identifies password hash without exposing it in the
+// tokens
+func passwordFingerprint(hash string) string {
+	sum := sha256.Sum256([]byte(hash))
+	return hex.EncodeToString(sum[:8])
+}
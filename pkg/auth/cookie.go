@@ -35,9 +35,9 @@ func (c *CookieOptions) Expired() *http.Cookie {
 	return cookie
 }
 
-func CookieSchema(secret []byte, lifetime time.Duration) *cookieSchema {
+func CookieSchema(keys *KeySet, lifetime time.Duration) *cookieSchema {
 	return &cookieSchema{
-		secret:   secret,
+		keys:     keys,
 		lifetime: lifetime,
 
 		Cookie: CookieOptions{
@@ -49,7 +49,7 @@ func CookieSchema(secret []byte, lifetime time.Duration) *cookieSchema {
 }
 
 type cookieSchema struct {
-	secret   []byte
+	keys     *KeySet
 	lifetime time.Duration
 
 	Cookie   CookieOptions
@@ -57,21 +57,18 @@ type cookieSchema struct {
 	Audience string
 }
 
-func (s *cookieSchema) SignIn(c echo.Context, claims Claims) error {
+func (s *cookieSchema) SignIn(c echo.Context, user Claims) error {
 	jwtClaims := &jwt.StandardClaims{
 		Id:        uuid.New().String(),
 		IssuedAt:  time.Now().Unix(),
 		ExpiresAt: time.Now().Add(s.lifetime).Unix(),
-		Subject:   claims.Id(),
+		Subject:   user.Id(),
 		Issuer:    s.Issuer,
 		Audience:  s.Audience,
 	}
 
-	// Create token with claims
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwtClaims)
-
 	// Generate signed and encoded token
-	t, err := token.SignedString(s.secret)
+	t, err := s.keys.Sign(jwtClaims)
 	if err != nil {
 		return err
 	}
@@ -80,7 +77,7 @@ func (s *cookieSchema) SignIn(c echo.Context, claims Claims) error {
 	c.SetCookie(s.Cookie.Cookie(t))
 
 	// Save claims to context
-	c.Set(ClaimsKey, claims)
+	c.Set(ClaimsKey, &claims{id: user.Id(), sessionId: jwtClaims.Id})
 
 	return nil
 }
@@ -114,7 +111,7 @@ func (s *cookieSchema) Authorize(c echo.Context) Claims {
 		return nil
 	}
 
-	claims := &claims{jwtClaims.Subject}
+	claims := &claims{id: jwtClaims.Subject, sessionId: jwtClaims.Id}
 
 	// Save claims to context
 	c.Set(ClaimsKey, claims)
@@ -135,5 +132,5 @@ func (s *cookieSchema) keyFunc(t *jwt.Token) (interface{}, error) {
 		return nil, fmt.Errorf("invalid jwt audience")
 	}
 
-	return s.secret, nil
+	return s.keys.Keyfunc(t)
 }
@@ -25,3 +25,13 @@ func GetUserId(c echo.Context) (string, error) {
 		return handler.GetUserId(c), nil
 	}
 }
+
+// GetSessionId returns current session id from context
+func GetSessionId(c echo.Context) (string, error) {
+	handler, err := GetHandler(c)
+	if err != nil {
+		return "", err
+	} else {
+		return handler.GetSessionId(c), nil
+	}
+}
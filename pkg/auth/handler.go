@@ -45,6 +45,34 @@ func (h *Handler) GetUserId(c echo.Context) string {
 	}
 }
 
+// GetSessionId returns ID of the current session or empty string when not
+// authenticated
+func (h *Handler) GetSessionId(c echo.Context) string {
+	if claims, ok := h.schema.Authorize(c).(interface{ SessionId() string }); ok {
+		return claims.SessionId()
+	} else {
+		return ""
+	}
+}
+
+// HasScope reports whether current user is authenticated and has the scope
+func (h *Handler) HasScope(c echo.Context, scope string) bool {
+	claims := h.schema.Authorize(c)
+	if claims == nil {
+		return false
+	}
+	scoped, ok := claims.(interface{ Scopes() []string })
+	if !ok || scoped.Scopes() == nil {
+		return true
+	}
+	for _, s := range scoped.Scopes() {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
 // Init injects handler to the context
 func (h *Handler) Init(next echo.HandlerFunc) echo.HandlerFunc {
 	return func(c echo.Context) error {
@@ -0,0 +1,218 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/golang-jwt/jwt"
+)
+
+// Minimum length of HMAC secrets in bytes
+const minSecretLength = 32
+
+var ErrUnknownKey = errors.New("unknown signing key")
+
+// Key is a JWT signing key identified by the "kid" header of the tokens
+type Key struct {
+	Id     string
+	Method jwt.SigningMethod
+
+	// SignKey is nil for keys used only for verifying tokens
+	SignKey   interface{}
+	VerifyKey interface{}
+}
+
+// HMACKey creates HS256 key from the given secret
+func HMACKey(id string, secret []byte) (Key, error) {
+	if len(secret) < minSecretLength {
+		return Key{}, fmt.Errorf("key %s: secret should be at least %v bytes long", id, minSecretLength)
+	}
+	return Key{Id: id, Method: jwt.SigningMethodHS256, SignKey: secret, VerifyKey: secret}, nil
+}
+
+// PEMKey creates RS256 or EdDSA key from PEM encoded private key. Keys
+// created from public keys are used only for verifying tokens.
+func PEMKey(id string, alg string, data []byte) (Key, error) {
+	key := Key{Id: id}
+	var err error
+
+	switch alg {
+	case jwt.SigningMethodRS256.Alg():
+		key.Method = jwt.SigningMethodRS256
+		var private *rsa.PrivateKey
+		if private, err = jwt.ParseRSAPrivateKeyFromPEM(data); err == nil {
+			key.SignKey, key.VerifyKey = private, &private.PublicKey
+		} else if key.VerifyKey, err = jwt.ParseRSAPublicKeyFromPEM(data); err != nil {
+			return key, fmt.Errorf("key %s: %s", id, err)
+		}
+	case jwt.SigningMethodEdDSA.Alg():
+		key.Method = jwt.SigningMethodEdDSA
+		var private crypto.PrivateKey
+		if private, err = jwt.ParseEdPrivateKeyFromPEM(data); err == nil {
+			key.SignKey, key.VerifyKey = private, private.(ed25519.PrivateKey).Public()
+		} else if key.VerifyKey, err = jwt.ParseEdPublicKeyFromPEM(data); err != nil {
+			return key, fmt.Errorf("key %s: %s", id, err)
+		}
+	default:
+		return key, fmt.Errorf("key %s: unsupported algorithm '%s'", id, alg)
+	}
+
+	return key, nil
+}
+
+// ParseKeys parses comma separated list of keys in "kid:alg:value" format.
+// Value is base64 encoded secret for HS256 keys and path of the PEM file for
+// RS256 and EdDSA keys.
+func ParseKeys(spec string) ([]Key, error) {
+	var keys []Key
+	for _, entry := range strings.Split(spec, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		parts := strings.SplitN(entry, ":", 3)
+		if len(parts) != 3 || parts[0] == "" {
+			return nil, fmt.Errorf("key '%s' should be in kid:alg:value format", entry)
+		}
+		id, alg, value := parts[0], parts[1], parts[2]
+
+		var key Key
+		var err error
+		if alg == jwt.SigningMethodHS256.Alg() {
+			var secret []byte
+			if secret, err = base64.StdEncoding.DecodeString(value); err != nil {
+				return nil, fmt.Errorf("key %s: secret is not valid base64: %s", id, err)
+			}
+			key, err = HMACKey(id, secret)
+		} else {
+			var data []byte
+			if data, err = os.ReadFile(value); err != nil {
+				return nil, fmt.Errorf("key %s: %s", id, err)
+			}
+			key, err = PEMKey(id, alg, data)
+		}
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+	return keys, nil
+}
+
+// KeySet signs tokens using the active key and verifies tokens signed by any
+// of its keys
+type KeySet struct {
+	active *Key
+	keys   map[string]*Key
+}
+
+// NewKeySet creates a key set signing tokens with the key identified by
+// active, the first key is used when active is empty
+func NewKeySet(active string, keys ...Key) (*KeySet, error) {
+	if len(keys) == 0 {
+		return nil, errors.New("at least one key is required")
+	}
+	if active == "" {
+		active = keys[0].Id
+	}
+
+	s := &KeySet{keys: make(map[string]*Key, len(keys))}
+	for i := range keys {
+		key := &keys[i]
+		if _, ok := s.keys[key.Id]; ok {
+			return nil, fmt.Errorf("duplicate key %s", key.Id)
+		}
+		s.keys[key.Id] = key
+	}
+
+	s.active = s.keys[active]
+	if s.active == nil {
+		return nil, fmt.Errorf("active key %s is not found", active)
+	}
+	if s.active.SignKey == nil {
+		return nil, fmt.Errorf("active key %s can't sign tokens", active)
+	}
+	return s, nil
+}
+
+// RandomKeySet creates a key set with a random HS256 key, tokens signed by
+// it are invalidated on restart
+func RandomKeySet() (*KeySet, error) {
+	secret := make([]byte, minSecretLength)
+	if _, err := rand.Read(secret); err != nil {
+		return nil, err
+	}
+	key, err := HMACKey("random", secret)
+	if err != nil {
+		return nil, err
+	}
+	return NewKeySet("", key)
+}
+
+// Sign signs claims using the active key
+func (s *KeySet) Sign(claims jwt.Claims) (string, error) {
+	token := jwt.NewWithClaims(s.active.Method, claims)
+	token.Header["kid"] = s.active.Id
+	return token.SignedString(s.active.SignKey)
+}
+
+// Keyfunc returns verification key of the token selected by its "kid"
+// header, tokens without kid or signed using different algorithm are rejected
+func (s *KeySet) Keyfunc(t *jwt.Token) (interface{}, error) {
+	id, _ := t.Header["kid"].(string)
+	key, ok := s.keys[id]
+	if !ok {
+		return nil, ErrUnknownKey
+	}
+	if t.Method.Alg() != key.Method.Alg() {
+		return nil, fmt.Errorf("unexpected signing method %s", t.Method.Alg())
+	}
+	return key.VerifyKey, nil
+}
+
+// JWK is a public key in JSON Web Key format, see RFC 7517
+type JWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Alg string `json:"alg"`
+	Use string `json:"use"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+}
+
+// PublicKeys returns public keys of the asymmetric keys in the set, so
+// other services could verify the tokens
+func (s *KeySet) PublicKeys() []JWK {
+	keys := make([]JWK, 0, len(s.keys))
+	for _, key := range s.keys {
+		jwk := JWK{Kid: key.Id, Alg: key.Method.Alg(), Use: "sig"}
+		switch public := key.VerifyKey.(type) {
+		case *rsa.PublicKey:
+			jwk.Kty = "RSA"
+			jwk.N = base64.RawURLEncoding.EncodeToString(public.N.Bytes())
+			jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(public.E)).Bytes())
+		case ed25519.PublicKey:
+			jwk.Kty = "OKP"
+			jwk.Crv = "Ed25519"
+			jwk.X = base64.RawURLEncoding.EncodeToString(public)
+		default:
+			// Secrets are never published
+			continue
+		}
+		keys = append(keys, jwk)
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i].Kid < keys[j].Kid })
+	return keys
+}
@@ -0,0 +1,246 @@
+package auth
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/golang-jwt/jwt"
+)
+
+func testSecret(b byte) []byte {
+	return bytes.Repeat([]byte{b}, minSecretLength)
+}
+
+func testHMACKey(t *testing.T, id string, b byte) Key {
+	key, err := HMACKey(id, testSecret(b))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key
+}
+
+// writeEdKeys writes PEM encoded private and public EdDSA keys to temporary
+// files and returns their paths
+func writeEdKeys(t *testing.T) (string, string) {
+	public, private, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	privateDer, err := x509.MarshalPKCS8PrivateKey(private)
+	if err != nil {
+		t.Fatal(err)
+	}
+	publicDer, err := x509.MarshalPKIXPublicKey(public)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dir := t.TempDir()
+	privatePath, publicPath := filepath.Join(dir, "private.pem"), filepath.Join(dir, "public.pem")
+	if err := os.WriteFile(privatePath, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: privateDer}), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(publicPath, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: publicDer}), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return privatePath, publicPath
+}
+
+func TestParseKeys(t *testing.T) {
+	privatePath, publicPath := writeEdKeys(t)
+	secret := base64.StdEncoding.EncodeToString(testSecret(1))
+
+	keys, err := ParseKeys(" new:EdDSA:" + privatePath + ", old:HS256:" + secret + ",, ext:EdDSA:" + publicPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(keys) != 3 {
+		t.Fatalf("got %v keys, want 3", len(keys))
+	}
+
+	tests := []struct {
+		id, alg string
+		signs   bool
+	}{
+		{"new", "EdDSA", true},
+		{"old", "HS256", true},
+		{"ext", "EdDSA", false},
+	}
+	for i, test := range tests {
+		key := keys[i]
+		if key.Id != test.id || key.Method.Alg() != test.alg || (key.SignKey != nil) != test.signs || key.VerifyKey == nil {
+			t.Errorf("got key %s %s signs %v, want %s %s signs %v", key.Id, key.Method.Alg(), key.SignKey != nil, test.id, test.alg, test.signs)
+		}
+	}
+}
+
+func TestParseKeysRejectsInvalidKeys(t *testing.T) {
+	tests := []struct {
+		name, spec string
+	}{
+		{"format", "key:HS256"},
+		{"empty kid", ":HS256:" + base64.StdEncoding.EncodeToString(testSecret(1))},
+		{"base64", "key:HS256:not base64"},
+		{"short secret", "key:HS256:" + base64.StdEncoding.EncodeToString([]byte("short"))},
+		{"missing file", "key:EdDSA:" + filepath.Join(t.TempDir(), "missing.pem")},
+		{"algorithm", "key:ES256:" + os.Args[0]},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if keys, err := ParseKeys(test.spec); err == nil {
+				t.Errorf("got %v keys, want error", len(keys))
+			}
+		})
+	}
+}
+
+func TestNewKeySet(t *testing.T) {
+	_, publicPath := writeEdKeys(t)
+	data, err := os.ReadFile(publicPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	verifyOnly, err := PEMKey("ext", "EdDSA", data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	first, second := testHMACKey(t, "first", 1), testHMACKey(t, "second", 2)
+
+	tests := []struct {
+		name   string
+		active string
+		keys   []Key
+		want   string
+	}{
+		{"first key by default", "", []Key{first, second}, "first"},
+		{"active key", "second", []Key{first, second}, "second"},
+		{"verify only keys are kept", "first", []Key{verifyOnly, first}, "first"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			s, err := NewKeySet(test.active, test.keys...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if s.active.Id != test.want {
+				t.Errorf("got active key %s, want %s", s.active.Id, test.want)
+			}
+			if len(s.keys) != len(test.keys) {
+				t.Errorf("got %v keys, want %v", len(s.keys), len(test.keys))
+			}
+		})
+	}
+
+	invalid := []struct {
+		name   string
+		active string
+		keys   []Key
+	}{
+		{"no keys", "", nil},
+		{"unknown active key", "third", []Key{first, second}},
+		{"verify only active key", "ext", []Key{first, verifyOnly}},
+		{"verify only first key", "", []Key{verifyOnly, first}},
+		{"duplicate kid", "", []Key{first, testHMACKey(t, "first", 2)}},
+	}
+	for _, test := range invalid {
+		t.Run(test.name, func(t *testing.T) {
+			if _, err := NewKeySet(test.active, test.keys...); err == nil {
+				t.Error("got key set, want error")
+			}
+		})
+	}
+}
+
+func TestKeyfunc(t *testing.T) {
+	s, err := NewKeySet("", testHMACKey(t, "key", 1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	claims := jwt.StandardClaims{Subject: "user"}
+
+	t.Run("valid", func(t *testing.T) {
+		signed, err := s.Sign(claims)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := jwt.Parse(signed, s.Keyfunc); err != nil {
+			t.Errorf("token is rejected: %s", err)
+		}
+	})
+
+	t.Run("unknown kid", func(t *testing.T) {
+		for _, kid := range []interface{}{nil, "other"} {
+			token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+			if kid != nil {
+				token.Header["kid"] = kid
+			}
+			signed, err := token.SignedString(testSecret(1))
+			if err != nil {
+				t.Fatal(err)
+			}
+			_, err = jwt.Parse(signed, s.Keyfunc)
+			if ve, ok := err.(*jwt.ValidationError); !ok || ve.Inner != ErrUnknownKey {
+				t.Errorf("kid %v: got %v, want %v", kid, err, ErrUnknownKey)
+			}
+		}
+	})
+
+	t.Run("algorithm mismatch", func(t *testing.T) {
+		// Token claims to be signed by the key using a different algorithm
+		token := jwt.NewWithClaims(jwt.SigningMethodHS512, claims)
+		token.Header["kid"] = "key"
+		signed, err := token.SignedString(testSecret(1))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := jwt.Parse(signed, s.Keyfunc); err == nil {
+			t.Error("token signed using HS512 is accepted")
+		}
+	})
+}
+
+func TestKeyRotation(t *testing.T) {
+	old := testHMACKey(t, "old", 1)
+	before, err := NewKeySet("", old)
+	if err != nil {
+		t.Fatal(err)
+	}
+	oldToken, err := before.Sign(jwt.StandardClaims{Subject: "user"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// New key becomes active, the old one is kept for verifying tokens
+	// issued before the rotation
+	after, err := NewKeySet("new", old, testHMACKey(t, "new", 2))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := jwt.Parse(oldToken, after.Keyfunc); err != nil {
+		t.Errorf("token signed by the old key is rejected: %s", err)
+	}
+
+	newToken, err := after.Sign(jwt.StandardClaims{Subject: "user"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	token, err := jwt.Parse(newToken, after.Keyfunc)
+	if err != nil {
+		t.Fatalf("token signed by the new key is rejected: %s", err)
+	}
+	if kid := token.Header["kid"]; kid != "new" {
+		t.Errorf("token is signed by %v, want new", kid)
+	}
+
+	// Tokens signed by the new key aren't accepted before the rotation
+	if _, err := jwt.Parse(newToken, before.Keyfunc); err == nil {
+		t.Error("token signed by the new key is accepted before the rotation")
+	}
+}
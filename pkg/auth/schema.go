@@ -17,9 +17,23 @@ type Schema interface {
 }
 
 type claims struct {
-	id string
+	id        string
+	sessionId string
+
+	// scopes limit access of the claims, nil when unlimited
+	scopes []string
 }
 
 func (c *claims) Id() string {
 	return c.id
 }
+
+// Scopes returns scopes granted to the claims, nil when unlimited
+func (c *claims) Scopes() []string {
+	return c.scopes
+}
+
+// SessionId returns id of the token the claims are read from
+func (c *claims) SessionId() string {
+	return c.sessionId
+}
@@ -0,0 +1,101 @@
+package auth
+
+import (
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/themisir/myfeed/pkg/adding"
+	"github.com/themisir/myfeed/pkg/listing"
+)
+
+// Context key marking that session of the request is already checked
+const sessionCheckedKey = "auth.session"
+
+// Minimum time between updates of the session last seen time
+const touchInterval = time.Minute
+
+// SessionStore records sessions signed in using SessionSchema
+type SessionStore interface {
+	AddSession(data adding.SessionData) error
+	GetSession(id string, now time.Time) (listing.Session, error)
+	TouchSession(id string, seenAt time.Time, ip string) error
+	RemoveUserSession(userId string, id string) error
+}
+
+// SessionSchema wraps cookie schema recording every issued token in the
+// store. Tokens are accepted only while their sessions are present in the
+// store, so removing a session revokes its token.
+func SessionSchema(cookie *cookieSchema, store SessionStore) *sessionSchema {
+	return &sessionSchema{cookie, store}
+}
+
+type sessionSchema struct {
+	cookie *cookieSchema
+	store  SessionStore
+}
+
+func (s *sessionSchema) SignIn(c echo.Context, user Claims) error {
+	if err := s.cookie.SignIn(c, user); err != nil {
+		return err
+	}
+
+	claims := c.Get(ClaimsKey).(*claims)
+	now := time.Now()
+	err := s.store.AddSession(adding.SessionData{
+		Id:        claims.sessionId,
+		UserId:    claims.id,
+		IP:        c.RealIP(),
+		UserAgent: c.Request().UserAgent(),
+		CreatedAt: now,
+		ExpiresAt: now.Add(s.cookie.lifetime),
+	})
+	if err != nil {
+		s.cookie.SignOut(c)
+		return err
+	}
+
+	c.Set(sessionCheckedKey, true)
+	return nil
+}
+
+func (s *sessionSchema) SignOut(c echo.Context) error {
+	if claims, ok := s.Authorize(c).(*claims); ok {
+		if err := s.store.RemoveUserSession(claims.id, claims.sessionId); err != nil {
+			return err
+		}
+	}
+	return s.cookie.SignOut(c)
+}
+
+func (s *sessionSchema) Authorize(c echo.Context) Claims {
+	// Check retrieving checked claims from context
+	if checked, _ := c.Get(sessionCheckedKey).(bool); checked {
+		claims, _ := c.Get(ClaimsKey).(Claims)
+		return claims
+	}
+	c.Set(sessionCheckedKey, true)
+
+	claims, ok := s.cookie.Authorize(c).(*claims)
+	if !ok {
+		return nil
+	}
+
+	// Check whether session is revoked
+	now := time.Now()
+	session, err := s.store.GetSession(claims.sessionId, now)
+	if err != nil || session.UserId() != claims.id {
+		if err != nil && err != listing.ErrNotFound {
+			c.Logger().Errorf("Failed to get session: %s", err)
+		}
+		c.Set(ClaimsKey, nil)
+		return nil
+	}
+
+	if now.Sub(session.LastSeenAt()) > touchInterval {
+		if err := s.store.TouchSession(session.Id(), now, c.RealIP()); err != nil {
+			c.Logger().Warnf("Failed to update session: %s", err)
+		}
+	}
+
+	return claims
+}
@@ -0,0 +1,141 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/themisir/myfeed/pkg/listing"
+)
+
+// Scopes granted to personal access tokens. Every token can read, cookie
+// sessions aren't limited by scopes.
+const (
+	ScopeRead       = "read"
+	ScopeFeedsWrite = "feeds:write"
+
+	// ScopeAccount is required for managing the account, it's never granted
+	// to tokens
+	ScopeAccount = "account"
+)
+
+// Prefix of the generated tokens, makes leaked tokens easier to recognize
+const tokenPrefix = "myfeed_"
+
+// Context key marking that bearer token of the request is already checked
+const bearerCheckedKey = "auth.bearer"
+
+var ErrNotSupported = errors.New("operation is not supported by the schema")
+
+// NewToken generates a random token returning the token and its hash
+func NewToken() (token string, hash string, err error) {
+	b := make([]byte, 32)
+	if _, err = rand.Read(b); err != nil {
+		return
+	}
+	token = tokenPrefix + base64.RawURLEncoding.EncodeToString(b)
+	return token, HashToken(token), nil
+}
+
+// HashToken returns hash of the token for storing and lookups, tokens are
+// random so they don't need a slow hash
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// TokenStore finds tokens accepted by BearerSchema
+type TokenStore interface {
+	FindTokenByHash(hash string) (listing.Token, error)
+	TouchToken(id int, usedAt time.Time) error
+}
+
+// BearerSchema authorizes requests with personal access tokens passed in the
+// Authorization header. Tokens are created by users, so the schema doesn't
+// support signing in or out.
+func BearerSchema(store TokenStore) *bearerSchema {
+	return &bearerSchema{store}
+}
+
+type bearerSchema struct {
+	store TokenStore
+}
+
+func (s *bearerSchema) SignIn(c echo.Context, claims Claims) error {
+	return ErrNotSupported
+}
+
+func (s *bearerSchema) SignOut(c echo.Context) error {
+	return ErrNotSupported
+}
+
+func (s *bearerSchema) Authorize(c echo.Context) Claims {
+	// Check retrieving checked claims from context
+	if checked, _ := c.Get(bearerCheckedKey).(bool); checked {
+		claims, _ := c.Get(ClaimsKey).(Claims)
+		return claims
+	}
+	c.Set(bearerCheckedKey, true)
+
+	header := c.Request().Header.Get(echo.HeaderAuthorization)
+	if len(header) < 7 || !strings.EqualFold(header[:7], "bearer ") {
+		return nil
+	}
+
+	token, err := s.store.FindTokenByHash(HashToken(strings.TrimSpace(header[7:])))
+	if err != nil {
+		if err != listing.ErrNotFound {
+			c.Logger().Errorf("Failed to find token: %s", err)
+		}
+		return nil
+	}
+
+	if last := token.LastUsedAt(); last == nil || time.Since(*last) > touchInterval {
+		if err := s.store.TouchToken(token.Id(), time.Now()); err != nil {
+			c.Logger().Warnf("Failed to update token: %s", err)
+		}
+	}
+
+	scopes := token.Scopes()
+	if scopes == nil {
+		scopes = []string{}
+	}
+	claims := &claims{id: token.UserId(), scopes: scopes}
+
+	// Save claims to context
+	c.Set(ClaimsKey, claims)
+
+	return claims
+}
+
+// CompositeSchema authorizes requests using the first of the schemas
+// accepting them, signing in and out is done using the first schema
+func CompositeSchema(schemas ...Schema) *compositeSchema {
+	return &compositeSchema{schemas}
+}
+
+type compositeSchema struct {
+	schemas []Schema
+}
+
+func (s *compositeSchema) SignIn(c echo.Context, claims Claims) error {
+	return s.schemas[0].SignIn(c, claims)
+}
+
+func (s *compositeSchema) SignOut(c echo.Context) error {
+	return s.schemas[0].SignOut(c)
+}
+
+func (s *compositeSchema) Authorize(c echo.Context) Claims {
+	for _, schema := range s.schemas {
+		if claims := schema.Authorize(c); claims != nil {
+			return claims
+		}
+	}
+	return nil
+}
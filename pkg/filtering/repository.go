@@ -0,0 +1,206 @@
+package filtering
+
+import (
+	"github.com/themisir/myfeed/pkg/listing"
+	"github.com/themisir/myfeed/pkg/models"
+)
+
+// Maximum number of additional batches fetched for filling a page of
+// filtered posts
+const maxBatches = 10
+
+// Unread posts of the feeds having rules are counted by scanning them in
+// batches, up to maxUnreadScan posts per feed
+const (
+	unreadBatchSize = 200
+	maxUnreadScan   = 1000
+)
+
+// RuleRepository provides rules of the feeds
+type RuleRepository interface {
+	GetFeedRules(feedId int) ([]listing.FeedRule, error)
+}
+
+// NewPostRepository wraps posts repository applying feed rules to the feed
+// post listings
+func NewPostRepository(posts models.PostRepository, rules RuleRepository) models.PostRepository {
+	return &postRepository{posts, rules}
+}
+
+type postRepository struct {
+	models.PostRepository
+	rules RuleRepository
+}
+
+// GetFeedPosts returns posts shown by the feed rules. Posts are fetched in
+// batches until the page is filled. When most of the posts are hidden and the
+// page can't be filled in maxBatches, cursor of the last scanned post is
+// returned, so listing can continue from it.
+func (r *postRepository) GetFeedPosts(feedId int, page listing.Page) ([]listing.SourcePost, *listing.Cursor, error) {
+	rules, err := r.rules.GetFeedRules(feedId)
+	if err != nil {
+		return nil, nil, err
+	}
+	filter, err := Compile(FromFeedRules(rules))
+	if err != nil || filter.Empty() {
+		// Invalid rules are rejected when saved, show every post otherwise
+		return r.PostRepository.GetFeedPosts(feedId, page)
+	}
+
+	var result []listing.SourcePost
+	var scanned *listing.Cursor
+	batch := page
+	for i := 0; i <= maxBatches && len(result) < page.Limit; i++ {
+		posts, _, err := r.PostRepository.GetFeedPosts(feedId, batch)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		shown := make([]listing.SourcePost, 0, len(posts))
+		for _, post := range posts {
+			if _, hidden := filter.Hides(post); !hidden {
+				shown = append(shown, post)
+			}
+		}
+
+		// Posts are returned from the newest to the oldest, so batches of
+		// newer posts are prepended
+		if page.After != nil {
+			result = append(shown, result...)
+		} else {
+			result = append(result, shown...)
+		}
+
+		if len(posts) < batch.Limit {
+			scanned = nil
+			break
+		}
+
+		// Continue from the last fetched post
+		if page.After != nil {
+			cursor := posts[0].Cursor()
+			batch.After = &cursor
+			scanned = &cursor
+		} else {
+			cursor := posts[len(posts)-1].Cursor()
+			batch.Before = &cursor
+			scanned = &cursor
+		}
+	}
+
+	// Keep posts closest to the cursor
+	if len(result) >= page.Limit {
+		scanned = nil
+		if page.After != nil {
+			result = result[len(result)-page.Limit:]
+		} else {
+			result = result[:page.Limit]
+		}
+	}
+
+	return result, scanned, nil
+}
+
+// SearchPosts removes posts hidden by the feed rules from the results of the
+// feed searches. Results are fetched from the start in batches until the page
+// is filled, so offset counts shown results only.
+func (r *postRepository) SearchPosts(search listing.Search) ([]listing.SearchResult, error) {
+	if search.Scope != listing.SearchFeed {
+		return r.PostRepository.SearchPosts(search)
+	}
+
+	rules, err := r.rules.GetFeedRules(search.FeedId)
+	if err != nil {
+		return nil, err
+	}
+	filter, err := Compile(FromFeedRules(rules))
+	if err != nil || filter.Empty() {
+		return r.PostRepository.SearchPosts(search)
+	}
+
+	var shown []listing.SearchResult
+	skip := search.Offset
+	batch := search
+	batch.Offset = 0
+	batch.Limit = search.Offset + search.Limit
+	for i := 0; i <= maxBatches && len(shown) < search.Limit; i++ {
+		results, err := r.PostRepository.SearchPosts(batch)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, result := range results {
+			if _, hidden := filter.Hides(result); hidden {
+				continue
+			}
+			if skip > 0 {
+				skip--
+			} else if len(shown) < search.Limit {
+				shown = append(shown, result)
+			}
+		}
+
+		if len(results) < batch.Limit {
+			break
+		}
+		batch.Offset += len(results)
+	}
+	return shown, nil
+}
+
+// GetUnreadCounts removes posts hidden by the feed rules from the unread
+// counts. Unread posts of the feeds having rules are scanned, so only the
+// first maxUnreadScan of them are counted.
+func (r *postRepository) GetUnreadCounts(userId string) (map[int]int, error) {
+	counts, err := r.PostRepository.GetUnreadCounts(userId)
+	if err != nil {
+		return nil, err
+	}
+
+	for feedId := range counts {
+		rules, err := r.rules.GetFeedRules(feedId)
+		if err != nil {
+			return nil, err
+		}
+		filter, err := Compile(FromFeedRules(rules))
+		if err != nil || filter.Empty() {
+			continue
+		}
+
+		shown, err := r.countShown(feedId, userId, filter)
+		if err != nil {
+			return nil, err
+		}
+		if shown > 0 {
+			counts[feedId] = shown
+		} else {
+			delete(counts, feedId)
+		}
+	}
+	return counts, nil
+}
+
+// countShown returns number of the unread feed posts shown by the filter
+func (r *postRepository) countShown(feedId int, userId string, filter *Filter) (int, error) {
+	shown := 0
+	page := listing.Page{Limit: unreadBatchSize, UserId: userId, Unread: true}
+	for scanned := 0; scanned < maxUnreadScan; {
+		posts, _, err := r.PostRepository.GetFeedPosts(feedId, page)
+		if err != nil {
+			return 0, err
+		}
+		for _, post := range posts {
+			if _, hidden := filter.Hides(post); !hidden {
+				shown++
+			}
+		}
+
+		scanned += len(posts)
+		if len(posts) < page.Limit {
+			break
+		}
+		cursor := posts[len(posts)-1].Cursor()
+		page.Before = &cursor
+	}
+	return shown, nil
+}
@@ -0,0 +1,333 @@
+package filtering
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/themisir/myfeed/pkg/listing"
+	"github.com/themisir/myfeed/pkg/models"
+)
+
+type testPost struct {
+	listing.SourcePost
+	id    int
+	title string
+	read  bool
+}
+
+func (p *testPost) Id() int                { return p.id }
+func (p *testPost) Title() string          { return p.title }
+func (p *testPost) Description() string    { return "" }
+func (p *testPost) Url() string            { return fmt.Sprintf("https://example.com/%v", p.id) }
+func (p *testPost) Author() string         { return "" }
+func (p *testPost) Categories() []string   { return nil }
+func (p *testPost) Source() listing.Source { return nil }
+func (p *testPost) Read() bool             { return p.read }
+
+// Newer posts have greater ids
+func (p *testPost) Cursor() listing.Cursor {
+	return listing.Cursor{Time: time.Unix(int64(p.id)*60, 0).UTC(), Id: p.id}
+}
+
+// fakePosts lists posts of a single feed, other methods aren't used
+type fakePosts struct {
+	models.PostRepository
+	posts []*testPost // from the oldest to the newest
+	calls int
+}
+
+// newFakePosts creates posts with the given titles from the oldest to the
+// newest
+func newFakePosts(titles ...string) *fakePosts {
+	r := &fakePosts{}
+	for i, title := range titles {
+		r.posts = append(r.posts, &testPost{id: i + 1, title: title})
+	}
+	return r
+}
+
+func (r *fakePosts) GetFeedPosts(feedId int, page listing.Page) ([]listing.SourcePost, *listing.Cursor, error) {
+	r.calls++
+
+	var selected []listing.SourcePost
+	if page.After != nil {
+		// Oldest posts newer than the cursor
+		for _, post := range r.posts {
+			if post.id > page.After.Id && !(page.Unread && post.read) && len(selected) < page.Limit {
+				selected = append([]listing.SourcePost{post}, selected...)
+			}
+		}
+		return selected, nil, nil
+	}
+
+	for i := len(r.posts) - 1; i >= 0; i-- {
+		post := r.posts[i]
+		if page.Before != nil && post.id >= page.Before.Id || page.Unread && post.read {
+			continue
+		}
+		if len(selected) < page.Limit {
+			selected = append(selected, post)
+		}
+	}
+	return selected, nil, nil
+}
+
+func (r *fakePosts) GetUnreadCounts(userId string) (map[int]int, error) {
+	count := 0
+	for _, post := range r.posts {
+		if !post.read {
+			count++
+		}
+	}
+	return map[int]int{1: count}, nil
+}
+
+type fakeRules []listing.FeedRule
+
+func (r fakeRules) GetFeedRules(feedId int) ([]listing.FeedRule, error) {
+	return r, nil
+}
+
+type testRule struct{ rule Rule }
+
+func (r testRule) Id() int         { return 0 }
+func (r testRule) Action() string  { return r.rule.Action }
+func (r testRule) Field() string   { return r.rule.Field }
+func (r testRule) Matcher() string { return r.rule.Matcher }
+func (r testRule) Pattern() string { return r.rule.Pattern }
+
+// excludeTitle hides posts which title contains the pattern
+func excludeTitle(pattern string) fakeRules {
+	return fakeRules{testRule{Rule{ActionExclude, FieldTitle, MatcherSubstring, pattern}}}
+}
+
+func TestUnreadCountsExcludeHiddenPosts(t *testing.T) {
+	posts := newFakePosts("shown", "hidden", "shown", "hidden", "shown")
+	posts.posts[0].read = true
+
+	counts, err := NewPostRepository(posts, excludeTitle("hidden")).GetUnreadCounts("user")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if counts[1] != 2 {
+		t.Errorf("got %v unread posts, want 2", counts[1])
+	}
+
+	// Feeds without unread shown posts aren't counted, every title
+	// contains "h"
+	counts, err = NewPostRepository(posts, excludeTitle("h")).GetUnreadCounts("user")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := counts[1]; ok {
+		t.Errorf("got %v unread posts, want none", counts[1])
+	}
+}
+
+func TestUnreadCountsScanLimit(t *testing.T) {
+	titles := make([]string, maxUnreadScan+unreadBatchSize)
+	for i := range titles {
+		titles[i] = "shown"
+	}
+	posts := newFakePosts(titles...)
+
+	counts, err := NewPostRepository(posts, excludeTitle("hidden")).GetUnreadCounts("user")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if counts[1] != maxUnreadScan {
+		t.Errorf("got %v unread posts, want %v", counts[1], maxUnreadScan)
+	}
+	if posts.calls != maxUnreadScan/unreadBatchSize {
+		t.Errorf("posts are listed %v times, want %v", posts.calls, maxUnreadScan/unreadBatchSize)
+	}
+}
+
+// pageIds returns ids of the posts
+func pageIds(posts []listing.SourcePost) []int {
+	ids := make([]int, len(posts))
+	for i, post := range posts {
+		ids[i] = post.Id()
+	}
+	return ids
+}
+
+func TestGetFeedPostsWithoutRules(t *testing.T) {
+	posts := newFakePosts("a", "b", "c")
+	result, scanned, err := NewPostRepository(posts, fakeRules{}).GetFeedPosts(1, listing.Page{Limit: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ids := pageIds(result); !reflect.DeepEqual(ids, []int{3, 2}) || scanned != nil || posts.calls != 1 {
+		t.Errorf("got %v, scanned %v after %v calls", ids, scanned, posts.calls)
+	}
+}
+
+func TestGetFeedPostsRefillsPages(t *testing.T) {
+	// Posts 1 to 12, every third one is shown
+	titles := make([]string, 12)
+	for i := range titles {
+		titles[i] = "hidden"
+		if (i+1)%3 == 0 {
+			titles[i] = "shown"
+		}
+	}
+
+	tests := []struct {
+		name  string
+		page  listing.Page
+		want  []int
+		calls int
+	}{
+		{"first page", listing.Page{Limit: 3}, []int{12, 9, 6}, 3},
+		{"before", listing.Page{Limit: 2, Before: &listing.Cursor{Id: 9}}, []int{6, 3}, 3},
+		{"after", listing.Page{Limit: 2, After: &listing.Cursor{Id: 3}}, []int{9, 6}, 3},
+		{"last page", listing.Page{Limit: 3, Before: &listing.Cursor{Id: 6}}, []int{3}, 2},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			posts := newFakePosts(titles...)
+			result, scanned, err := NewPostRepository(posts, excludeTitle("hidden")).GetFeedPosts(1, test.page)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if ids := pageIds(result); !reflect.DeepEqual(ids, test.want) {
+				t.Errorf("got %v, want %v", ids, test.want)
+			}
+			if scanned != nil {
+				t.Errorf("got scanned cursor %v for a complete page", scanned)
+			}
+			if posts.calls != test.calls {
+				t.Errorf("posts are listed %v times, want %v", posts.calls, test.calls)
+			}
+		})
+	}
+}
+
+func TestGetFeedPostsReturnsScannedCursor(t *testing.T) {
+	// Shown posts are separated by more hidden posts than scanned at once
+	const limit = 2
+	gap := limit * (maxBatches + 2)
+	titles := make([]string, 2*gap+1)
+	for i := range titles {
+		titles[i] = "hidden"
+	}
+	titles[0], titles[gap] = "shown", "shown"
+	newest := len(titles)
+
+	t.Run("before", func(t *testing.T) {
+		posts := newFakePosts(titles...)
+		result, scanned, err := NewPostRepository(posts, excludeTitle("hidden")).GetFeedPosts(1, listing.Page{Limit: limit})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(result) != 0 {
+			t.Errorf("got %v, want no posts", pageIds(result))
+		}
+
+		// Listing continues from the oldest scanned post
+		want := newest - limit*(maxBatches+1) + 1
+		if scanned == nil || scanned.Id != want {
+			t.Fatalf("got scanned cursor %v, want post %v", scanned, want)
+		}
+
+		result, _, err = NewPostRepository(posts, excludeTitle("hidden")).GetFeedPosts(1, listing.Page{Limit: limit, Before: scanned})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if ids := pageIds(result); !reflect.DeepEqual(ids, []int{gap + 1}) {
+			t.Errorf("got %v, want [%v]", ids, gap+1)
+		}
+	})
+
+	t.Run("after", func(t *testing.T) {
+		posts := newFakePosts(titles...)
+		after := listing.Cursor{Id: 1}
+		result, scanned, err := NewPostRepository(posts, excludeTitle("hidden")).GetFeedPosts(1, listing.Page{Limit: limit, After: &after})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(result) != 0 {
+			t.Errorf("got %v, want no posts", pageIds(result))
+		}
+
+		// Listing continues from the newest scanned post
+		want := 1 + limit*(maxBatches+1)
+		if scanned == nil || scanned.Id != want {
+			t.Errorf("got scanned cursor %v, want post %v", scanned, want)
+		}
+	})
+
+	t.Run("exhausted", func(t *testing.T) {
+		posts := newFakePosts(titles...)
+		before := listing.Cursor{Id: 2 * limit}
+		result, scanned, err := NewPostRepository(posts, excludeTitle("hidden")).GetFeedPosts(1, listing.Page{Limit: limit, Before: &before})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if ids := pageIds(result); !reflect.DeepEqual(ids, []int{1}) || scanned != nil {
+			t.Errorf("got %v, scanned %v, want [1] without cursor", ids, scanned)
+		}
+	})
+}
+
+type testResult struct {
+	*testPost
+}
+
+func (r testResult) TitleHeadline() string       { return r.title }
+func (r testResult) DescriptionHeadline() string { return "" }
+
+// SearchPosts returns every post from the newest to the oldest
+func (r *fakePosts) SearchPosts(search listing.Search) ([]listing.SearchResult, error) {
+	r.calls++
+
+	var results []listing.SearchResult
+	for i := len(r.posts) - 1 - search.Offset; i >= 0 && len(results) < search.Limit; i-- {
+		results = append(results, testResult{r.posts[i]})
+	}
+	return results, nil
+}
+
+func TestSearchPostsRefillsPages(t *testing.T) {
+	// Posts 1 to 12, every third one is shown
+	titles := make([]string, 12)
+	for i := range titles {
+		titles[i] = "hidden"
+		if (i+1)%3 == 0 {
+			titles[i] = "shown"
+		}
+	}
+
+	tests := []struct {
+		offset, limit int
+		want          []int
+	}{
+		{0, 2, []int{12, 9}},
+		{2, 2, []int{6, 3}},
+		{3, 2, []int{3}},
+		{4, 2, nil},
+	}
+	for _, test := range tests {
+		posts := newFakePosts(titles...)
+		results, err := NewPostRepository(posts, excludeTitle("hidden")).SearchPosts(listing.Search{
+			Scope:  listing.SearchFeed,
+			FeedId: 1,
+			Offset: test.offset,
+			Limit:  test.limit,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var ids []int
+		for _, result := range results {
+			ids = append(ids, result.Id())
+		}
+		if !reflect.DeepEqual(ids, test.want) {
+			t.Errorf("offset %v: got %v, want %v", test.offset, ids, test.want)
+		}
+	}
+}
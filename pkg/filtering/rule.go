@@ -0,0 +1,196 @@
+package filtering
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/themisir/myfeed/pkg/listing"
+	"github.com/themisir/myfeed/pkg/sources"
+)
+
+// Actions applied to the matching posts
+const (
+	ActionInclude = "include"
+	ActionExclude = "exclude"
+)
+
+// Post fields rules match on
+const (
+	FieldTitle       = "title"
+	FieldDescription = "description"
+	FieldUrl         = "url"
+	FieldAuthor      = "author"
+	FieldCategory    = "category"
+	FieldSource      = "source"
+)
+
+// Matchers comparing field values to the rule pattern
+const (
+	MatcherSubstring = "substring"
+	MatcherWord      = "word"
+	MatcherRegex     = "regex"
+)
+
+var (
+	Actions  = []string{ActionInclude, ActionExclude}
+	Fields   = []string{FieldTitle, FieldDescription, FieldUrl, FieldAuthor, FieldCategory, FieldSource}
+	Matchers = []string{MatcherSubstring, MatcherWord, MatcherRegex}
+)
+
+type Rule struct {
+	Action  string
+	Field   string
+	Matcher string
+	Pattern string
+}
+
+// FromFeedRules converts stored feed rules
+func FromFeedRules(rules []listing.FeedRule) []Rule {
+	result := make([]Rule, len(rules))
+	for i, rule := range rules {
+		result[i] = Rule{
+			Action:  rule.Action(),
+			Field:   rule.Field(),
+			Matcher: rule.Matcher(),
+			Pattern: rule.Pattern(),
+		}
+	}
+	return result
+}
+
+// RuleError describes an invalid rule
+type RuleError struct {
+	// Index of the invalid rule
+	Index  int
+	Reason string
+}
+
+func (e *RuleError) Error() string {
+	return fmt.Sprintf("rule %v: %s", e.Index+1, e.Reason)
+}
+
+// Filter decides which posts are shown. Posts matching any of the exclude
+// rules are hidden. When there are include rules posts have to match at
+// least one of them to be shown.
+type Filter struct {
+	rules    []compiledRule
+	includes bool
+}
+
+type compiledRule struct {
+	Rule
+	match func(value string) bool
+}
+
+// NoRule is returned by Filter.Hides when post is hidden for not matching
+// any of the include rules
+const NoRule = -1
+
+// Compile validates rules and prepares them for matching
+func Compile(rules []Rule) (*Filter, error) {
+	filter := &Filter{rules: make([]compiledRule, len(rules))}
+
+	for i, rule := range rules {
+		if !contains(Actions, rule.Action) {
+			return nil, &RuleError{i, fmt.Sprintf("unknown action '%s'", rule.Action)}
+		}
+		if !contains(Fields, rule.Field) {
+			return nil, &RuleError{i, fmt.Sprintf("unknown field '%s'", rule.Field)}
+		}
+		if rule.Pattern == "" {
+			return nil, &RuleError{i, "pattern is empty"}
+		}
+
+		var match func(string) bool
+		switch rule.Matcher {
+		case MatcherSubstring:
+			pattern := strings.ToLower(rule.Pattern)
+			match = func(value string) bool {
+				return strings.Contains(strings.ToLower(value), pattern)
+			}
+		case MatcherWord:
+			// Unlike \b these boundaries also work for unicode letters and
+			// patterns starting or ending with punctuation
+			re, err := regexp.Compile(`(?i)(?:^|[^\p{L}\p{N}_])` + regexp.QuoteMeta(rule.Pattern) + `(?:$|[^\p{L}\p{N}_])`)
+			if err != nil {
+				return nil, &RuleError{i, err.Error()}
+			}
+			match = re.MatchString
+		case MatcherRegex:
+			re, err := regexp.Compile(rule.Pattern)
+			if err != nil {
+				return nil, &RuleError{i, err.Error()}
+			}
+			match = re.MatchString
+		default:
+			return nil, &RuleError{i, fmt.Sprintf("unknown matcher '%s'", rule.Matcher)}
+		}
+
+		filter.rules[i] = compiledRule{rule, match}
+		if rule.Action == ActionInclude {
+			filter.includes = true
+		}
+	}
+
+	return filter, nil
+}
+
+// Empty reports whether filter shows every post
+func (f *Filter) Empty() bool {
+	return len(f.rules) == 0
+}
+
+// Hides returns index of the rule hiding the post, NoRule when the post
+// doesn't match any of the include rules
+func (f *Filter) Hides(post listing.SourcePost) (int, bool) {
+	included := !f.includes
+	for i := range f.rules {
+		rule := &f.rules[i]
+		if !rule.matches(post) {
+			continue
+		}
+		if rule.Action == ActionExclude {
+			return i, true
+		}
+		included = true
+	}
+
+	if !included {
+		return NoRule, true
+	}
+	return 0, false
+}
+
+func (r *compiledRule) matches(post listing.SourcePost) bool {
+	switch r.Field {
+	case FieldTitle:
+		return r.match(post.Title())
+	case FieldDescription:
+		return r.match(sources.PlainText(post.Description()))
+	case FieldUrl:
+		return r.match(post.Url())
+	case FieldAuthor:
+		return r.match(post.Author())
+	case FieldCategory:
+		for _, category := range post.Categories() {
+			if r.match(category) {
+				return true
+			}
+		}
+	case FieldSource:
+		if source := post.Source(); source != nil {
+			return r.match(source.Title()) || r.match(source.Url())
+		}
+	}
+	return false
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
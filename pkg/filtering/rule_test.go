@@ -0,0 +1,163 @@
+package filtering
+
+import (
+	"testing"
+
+	"github.com/themisir/myfeed/pkg/listing"
+)
+
+type ruleSource struct {
+	listing.Source
+	title, url string
+}
+
+func (s ruleSource) Title() string { return s.title }
+func (s ruleSource) Url() string   { return s.url }
+
+type rulePost struct {
+	listing.SourcePost
+	title, description, url, author string
+	categories                      []string
+}
+
+func (p rulePost) Title() string        { return p.title }
+func (p rulePost) Description() string  { return p.description }
+func (p rulePost) Url() string          { return p.url }
+func (p rulePost) Author() string       { return p.author }
+func (p rulePost) Categories() []string { return p.categories }
+func (p rulePost) Source() listing.Source {
+	return ruleSource{title: "Go Blog", url: "https://go.dev/blog/feed.atom"}
+}
+
+func TestCompileRejectsInvalidRules(t *testing.T) {
+	tests := []struct {
+		name string
+		rule Rule
+	}{
+		{"action", Rule{"hide", FieldTitle, MatcherSubstring, "x"}},
+		{"field", Rule{ActionExclude, "body", MatcherSubstring, "x"}},
+		{"matcher", Rule{ActionExclude, FieldTitle, "glob", "x"}},
+		{"empty pattern", Rule{ActionExclude, FieldTitle, MatcherSubstring, ""}},
+		{"regex", Rule{ActionExclude, FieldTitle, MatcherRegex, "(unclosed"}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			valid := Rule{ActionInclude, FieldTitle, MatcherSubstring, "go"}
+			_, err := Compile([]Rule{valid, test.rule})
+			ruleErr, ok := err.(*RuleError)
+			if !ok || ruleErr.Index != 1 {
+				t.Errorf("got %v, want error of the second rule", err)
+			}
+		})
+	}
+}
+
+func TestMatchers(t *testing.T) {
+	tests := []struct {
+		matcher, pattern, value string
+		want                    bool
+	}{
+		{MatcherSubstring, "GO", "Learning golang", true},
+		{MatcherSubstring, "rust", "Learning golang", false},
+		{MatcherWord, "go", "Why Go is fast", true},
+		{MatcherWord, "go", "Learning golang", false},
+		{MatcherWord, "go", "go", true},
+		{MatcherWord, "go", "(Go)", true},
+		{MatcherWord, "go", "go_lang", false},
+		{MatcherWord, "go", "go2", false},
+		// Boundaries work for unicode letters unlike \b
+		{MatcherWord, "çay", "Bir çay lütfen", true},
+		{MatcherWord, "çay", "çaylar", false},
+		{MatcherWord, "ay", "çay", false},
+		// Patterns are matched literally
+		{MatcherWord, "c++", "Modern C++ features", true},
+		{MatcherWord, "a.b", "axb", false},
+		{MatcherRegex, `^v\d+\.\d+`, "v1.18 released", true},
+		{MatcherRegex, `^v\d+\.\d+`, "Release v1.18", false},
+		// Regexes are case sensitive unless they opt out
+		{MatcherRegex, `release`, "Release", false},
+		{MatcherRegex, `(?i)release`, "Release", true},
+	}
+	for _, test := range tests {
+		filter, err := Compile([]Rule{{ActionExclude, FieldTitle, test.matcher, test.pattern}})
+		if err != nil {
+			t.Fatalf("%s %q: %s", test.matcher, test.pattern, err)
+		}
+		if _, got := filter.Hides(rulePost{title: test.value}); got != test.want {
+			t.Errorf("%s %q on %q = %v, want %v", test.matcher, test.pattern, test.value, got, test.want)
+		}
+	}
+}
+
+func TestFields(t *testing.T) {
+	post := rulePost{
+		title:       "Title",
+		description: "<p>Hello <b>world</b></p>",
+		url:         "https://example.com/post",
+		author:      "Gopher",
+		categories:  []string{"news", "release"},
+	}
+	tests := []struct {
+		field, pattern string
+		want           bool
+	}{
+		{FieldTitle, "title", true},
+		// Descriptions are matched as plain text
+		{FieldDescription, "hello world", true},
+		{FieldDescription, "<b>", false},
+		{FieldUrl, "example.com", true},
+		{FieldAuthor, "gopher", true},
+		{FieldCategory, "release", true},
+		{FieldCategory, "sports", false},
+		{FieldSource, "go blog", true},
+		{FieldSource, "go.dev", true},
+	}
+	for _, test := range tests {
+		filter, err := Compile([]Rule{{ActionExclude, test.field, MatcherSubstring, test.pattern}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, got := filter.Hides(post); got != test.want {
+			t.Errorf("%s %q = %v, want %v", test.field, test.pattern, got, test.want)
+		}
+	}
+}
+
+func TestHides(t *testing.T) {
+	include := func(pattern string) Rule { return Rule{ActionInclude, FieldTitle, MatcherWord, pattern} }
+	exclude := func(pattern string) Rule { return Rule{ActionExclude, FieldTitle, MatcherWord, pattern} }
+
+	tests := []struct {
+		name   string
+		rules  []Rule
+		title  string
+		hidden bool
+		rule   int
+	}{
+		{"no rules", nil, "anything", false, 0},
+		{"excluded", []Rule{exclude("ads")}, "New ads", true, 0},
+		{"not excluded", []Rule{exclude("ads")}, "News", false, 0},
+		{"included", []Rule{include("go")}, "Go 1.18", false, 0},
+		{"not included", []Rule{include("go")}, "Rust 1.60", true, NoRule},
+		{"any include", []Rule{include("go"), include("rust")}, "Rust 1.60", false, 0},
+		// Exclude rules win over include rules regardless of the order
+		{"included and excluded", []Rule{include("go"), exclude("ads")}, "Go ads", true, 1},
+		{"excluded and included", []Rule{exclude("ads"), include("go")}, "Go ads", true, 0},
+		{"first exclude", []Rule{exclude("go"), exclude("ads")}, "Go ads", true, 0},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			filter, err := Compile(test.rules)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if filter.Empty() != (len(test.rules) == 0) {
+				t.Errorf("Empty() = %v", filter.Empty())
+			}
+			rule, hidden := filter.Hides(rulePost{title: test.title})
+			if hidden != test.hidden || hidden && rule != test.rule {
+				t.Errorf("got rule %v hidden %v, want rule %v hidden %v", rule, hidden, test.rule, test.hidden)
+			}
+		})
+	}
+}
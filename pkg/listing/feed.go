@@ -5,9 +5,26 @@ type Feed interface {
 	Name() string
 	UserId() string
 	IsPublic() bool
+
+	// Retention limits of the feed, nil values mean instance defaults and
+	// zero values mean unlimited
+	RetentionDays() *int
+	RetentionPosts() *int
+}
+
+// FeedRule decides whether posts are shown in the feed
+type FeedRule interface {
+	Id() int
+	Action() string
+	Field() string
+	Matcher() string
+	Pattern() string
 }
 
 type FeedRepository interface {
 	GetUserFeeds(userId string) ([]Feed, error)
 	GetFeed(feedId int) (Feed, error)
+
+	// GetFeedRules returns rules of the feed in the order they are defined
+	GetFeedRules(feedId int) ([]FeedRule, error)
 }
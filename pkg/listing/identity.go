@@ -0,0 +1,20 @@
+package listing
+
+import "time"
+
+type (
+	// Identity links user to the account on an external identity provider
+	Identity interface {
+		Provider() string
+		Subject() string
+		UserId() string
+		Email() string
+		CreatedAt() time.Time
+	}
+	IdentityRepository interface {
+		// FindIdentity returns ErrNotFound when the identity isn't linked
+		// to any user
+		FindIdentity(provider string, subject string) (Identity, error)
+		GetUserIdentities(userId string) ([]Identity, error)
+	}
+)
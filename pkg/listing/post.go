@@ -1,22 +1,134 @@
 package listing
 
-import "time"
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
 
 type Post interface {
 	Id() int
 	Title() string
 	Description() string
 	Url() string
+	Author() string
+	Categories() []string
 	PublishedAt() *time.Time
 	UpdatedAt() *time.Time
+
+	// Cursor returns position of the post in listings
+	Cursor() Cursor
 }
 
 type SourcePost interface {
 	Post
 	Source() Source
+
+	// Read and Saved report whether the post is read or saved by the user
+	// listing the posts, they're always false for search results
+	Read() bool
+	Saved() bool
+}
+
+// Cursor is a position in post listings, which are ordered by publish time,
+// falling back to creation time, and id from the newest to the oldest
+type Cursor struct {
+	Time time.Time
+	Id   int
+}
+
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// String encodes cursor for using in urls
+func (c Cursor) String() string {
+	return fmt.Sprintf("%d-%d", c.Time.UnixMicro(), c.Id)
+}
+
+// ParseCursor decodes cursor encoded using Cursor.String
+func ParseCursor(s string) (Cursor, error) {
+	parts := strings.SplitN(s, "-", 2)
+	if len(parts) != 2 {
+		return Cursor{}, ErrInvalidCursor
+	}
+	micros, err := strconv.ParseInt(parts[0], 10, 64)
+	if err != nil {
+		return Cursor{}, ErrInvalidCursor
+	}
+	id, err := strconv.Atoi(parts[1])
+	if err != nil {
+		return Cursor{}, ErrInvalidCursor
+	}
+	return Cursor{Time: time.UnixMicro(micros).UTC(), Id: id}, nil
+}
+
+// Page selects a range of posts. Posts older than Before or newer than After
+// are selected when set, otherwise the newest posts are selected. Posts are
+// always returned from the newest to the oldest.
+type Page struct {
+	Before *Cursor
+	After  *Cursor
+	Limit  int
+
+	// UserId is the user whose read state is returned, posts read by the
+	// user are skipped when Unread is set
+	UserId string
+	Unread bool
+}
+
+// Scopes of the post search
+const (
+	// SearchFeed searches posts of a single feed
+	SearchFeed = "feed"
+	// SearchUser searches posts of feeds owned by a user
+	SearchUser = "user"
+	// SearchPublic searches posts of public feeds
+	SearchPublic = "public"
+)
+
+// Search describes a full-text search query
+type Search struct {
+	Query string
+	Scope string
+
+	// FeedId is used with SearchFeed scope and UserId is used with
+	// SearchUser scope
+	FeedId int
+	UserId string
+
+	Offset int
+	Limit  int
+}
+
+// Delimiters wrapping matched terms in the search result headlines
+const (
+	HighlightStart = "\u27ea"
+	HighlightStop  = "\u27eb"
+)
+
+type SearchResult interface {
+	SourcePost
+
+	// Fragments of the post title and description with matched terms
+	// wrapped between HighlightStart and HighlightStop. Headlines are plain
+	// text.
+	TitleHeadline() string
+	DescriptionHeadline() string
 }
 
 type PostRepository interface {
-	GetSourcePosts(sourceId int) ([]Post, error)
-	GetFeedPosts(feedId int) ([]SourcePost, error)
+	GetSourcePosts(sourceId int, page Page) ([]Post, error)
+	// GetFeedPosts returns a page of feed posts. When listing stops before
+	// filling the page while more posts might exist, cursor of the last
+	// scanned post is returned as scanned and listing continues from it.
+	GetFeedPosts(feedId int, page Page) (posts []SourcePost, scanned *Cursor, err error)
+
+	// GetUnreadCounts returns number of posts unread by the user in each of
+	// the user feeds indexed by feed id. Like the listings, counts exclude
+	// posts hidden by the feed rules when rules are applied.
+	GetUnreadCounts(userId string) (map[int]int, error)
+
+	// SearchPosts returns posts matching the query ordered by relevance
+	SearchPosts(search Search) ([]SearchResult, error)
 }
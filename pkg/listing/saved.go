@@ -0,0 +1,31 @@
+package listing
+
+import "time"
+
+type (
+	// SavedItem is a copy of a post saved by the user, it's kept after the
+	// post itself is removed
+	SavedItem interface {
+		Id() int
+		// PostId is the id of the saved post, nil when the post is removed
+		PostId() *int
+		Title() string
+		Description() string
+		Url() string
+		Author() string
+		SourceTitle() string
+		SourceUrl() string
+		SiteUrl() string
+		PublishedAt() *time.Time
+		SavedAt() time.Time
+
+		// Cursor returns position of the item in listings, which are ordered
+		// by save time
+		Cursor() Cursor
+	}
+	SavedItemRepository interface {
+		// GetSavedItems returns items saved by the user from the most
+		// recently saved, every item is returned when page limit is zero
+		GetSavedItems(userId string, page Page) ([]SavedItem, error)
+	}
+)
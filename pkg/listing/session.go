@@ -0,0 +1,25 @@
+package listing
+
+import "time"
+
+type (
+	// Session is a signed in session of the user identified by the id of
+	// the session token
+	Session interface {
+		Id() string
+		UserId() string
+		IP() string
+		UserAgent() string
+		CreatedAt() time.Time
+		LastSeenAt() time.Time
+		ExpiresAt() time.Time
+	}
+	SessionRepository interface {
+		// GetSession returns session unless it's expired or revoked
+		GetSession(id string, now time.Time) (Session, error)
+
+		// GetUserSessions returns active sessions of the user from the
+		// most recently seen
+		GetUserSessions(userId string, now time.Time) ([]Session, error)
+	}
+)
@@ -1,15 +1,39 @@
 package listing
 
+import "time"
+
 type (
 	Source interface {
 		Id() int
 		Title() string
 		Url() string
+		Description() string
+		SiteUrl() string
+		ImageUrl() string
+		ETag() string
+		LastModified() string
+		NextFetchAt() time.Time
+
+		// Health of the source
+		LastSuccessAt() *time.Time
+		LastError() string
+		LastStatus() int
+		FailureCount() int
+
+		// Diagnostics describes problems found in the last fetched document
+		Diagnostics() string
+	}
+	// FeedSource is a source as it is configured within a feed
+	FeedSource interface {
+		Source
+		// CustomTitle overrides source title within the feed when not empty
+		CustomTitle() string
 	}
 	SourceRepository interface {
 		GetSource(sourceId int) (Source, error)
 		GetSources() ([]Source, error)
-		GetFeedSources(feedId int) ([]Source, error)
+		GetDueSources(now time.Time) ([]Source, error)
+		GetFeedSources(feedId int) ([]FeedSource, error)
 		FindSourceByUrl(url string) (Source, error)
 	}
 )
@@ -0,0 +1,22 @@
+package listing
+
+import "time"
+
+type (
+	// Token is a personal access token of the user
+	Token interface {
+		Id() int
+		UserId() string
+		Name() string
+		Scopes() []string
+		CreatedAt() time.Time
+		// LastUsedAt is nil when token is never used
+		LastUsedAt() *time.Time
+	}
+	TokenRepository interface {
+		// FindTokenByHash returns ErrNotFound when there's no token with
+		// the given hash
+		FindTokenByHash(hash string) (Token, error)
+		GetUserTokens(userId string) ([]Token, error)
+	}
+)
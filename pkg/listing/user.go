@@ -6,6 +6,7 @@ type (
 		Email() string
 		Username() string
 		PasswordHash() string
+		EmailVerified() bool
 	}
 	UserRepository interface {
 		GetUserById(id string) (User, error)
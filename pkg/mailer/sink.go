@@ -0,0 +1,50 @@
+package mailer
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/themisir/myfeed/pkg/log"
+)
+
+// File creates mailer writing messages to .eml files in the directory
+func File(dir string) (Mailer, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, err
+	}
+	return &fileMailer{dir}, nil
+}
+
+type fileMailer struct {
+	dir string
+}
+
+func (m *fileMailer) Send(msg *Message) error {
+	data, err := msg.Bytes()
+	if err != nil {
+		return err
+	}
+	name := fmt.Sprintf("%s-%s.eml", time.Now().UTC().Format("20060102T150405.000000000"), randomHex(4))
+	return os.WriteFile(filepath.Join(m.dir, name), data, 0o600)
+}
+
+// Log creates mailer writing messages to the logger. Messages are logged as
+// is including links with tokens, so it should be used only in development.
+func Log(logger log.Logger) Mailer {
+	return &logMailer{logger}
+}
+
+type logMailer struct {
+	logger log.Logger
+}
+
+func (m *logMailer) Send(msg *Message) error {
+	// Encoding validates the message like other mailers do
+	if _, err := msg.Bytes(); err != nil {
+		return err
+	}
+	m.logger.Infof("Email to %s with subject '%s':\n%s", msg.To, msg.Subject, msg.HTML)
+	return nil
+}
@@ -0,0 +1,13 @@
+package models
+
+import (
+	"github.com/themisir/myfeed/pkg/adding"
+	"github.com/themisir/myfeed/pkg/listing"
+	"github.com/themisir/myfeed/pkg/removing"
+)
+
+type IdentityRepository interface {
+	adding.IdentityRepository
+	listing.IdentityRepository
+	removing.IdentityRepository
+}
@@ -0,0 +1,15 @@
+package models
+
+import (
+	"github.com/themisir/myfeed/pkg/adding"
+	"github.com/themisir/myfeed/pkg/listing"
+	"github.com/themisir/myfeed/pkg/removing"
+	"github.com/themisir/myfeed/pkg/updating"
+)
+
+type SessionRepository interface {
+	adding.SessionRepository
+	listing.SessionRepository
+	removing.SessionRepository
+	updating.SessionRepository
+}
@@ -0,0 +1,15 @@
+package models
+
+import (
+	"github.com/themisir/myfeed/pkg/adding"
+	"github.com/themisir/myfeed/pkg/listing"
+	"github.com/themisir/myfeed/pkg/removing"
+	"github.com/themisir/myfeed/pkg/updating"
+)
+
+type TokenRepository interface {
+	adding.TokenRepository
+	listing.TokenRepository
+	removing.TokenRepository
+	updating.TokenRepository
+}
@@ -0,0 +1,7 @@
+package models
+
+import "github.com/themisir/myfeed/pkg/adding"
+
+type UsedTokenRepository interface {
+	adding.UsedTokenRepository
+}
@@ -3,9 +3,11 @@ package models
 import (
 	"github.com/themisir/myfeed/pkg/adding"
 	"github.com/themisir/myfeed/pkg/listing"
+	"github.com/themisir/myfeed/pkg/updating"
 )
 
 type UserRepository interface {
 	adding.UserRepository
 	listing.UserRepository
+	updating.UserRepository
 }
@@ -0,0 +1,259 @@
+package oidc
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+)
+
+// Maximum size of the responses read from providers
+const maxResponseSize = 1 << 20
+
+var defaultScopes = []string{"openid", "email", "profile"}
+
+// Config describes an OpenID Connect provider
+type Config struct {
+	// Name identifies the provider in urls and linked identities
+	Name string
+	// Title is the name displayed to the users
+	Title string
+
+	Issuer       string
+	ClientId     string
+	ClientSecret string
+
+	// Scopes requested in addition to "openid", defaults to email and
+	// profile
+	Scopes []string
+
+	// AutoProvision enables creating users signing in with unknown
+	// identities
+	AutoProvision bool
+
+	// HTTPClient is used for requests to the provider, http.DefaultClient
+	// is used when nil
+	HTTPClient *http.Client
+}
+
+// Metadata is the provider configuration document, see
+// https://openid.net/specs/openid-connect-discovery-1_0.html#ProviderMetadata
+type Metadata struct {
+	Issuer                string   `json:"issuer"`
+	AuthorizationEndpoint string   `json:"authorization_endpoint"`
+	TokenEndpoint         string   `json:"token_endpoint"`
+	JWKSURI               string   `json:"jwks_uri"`
+	TokenAuthMethods      []string `json:"token_endpoint_auth_methods_supported"`
+}
+
+// Provider performs authorization code flow with PKCE against an OpenID
+// Connect provider. Provider metadata is discovered on first use.
+type Provider struct {
+	Config
+
+	mu       sync.Mutex
+	metadata *Metadata
+	keys     *keySet
+}
+
+func NewProvider(config Config) *Provider {
+	if config.Title == "" {
+		config.Title = config.Name
+	}
+	if len(config.Scopes) == 0 {
+		config.Scopes = defaultScopes
+	}
+	return &Provider{Config: config}
+}
+
+func (p *Provider) client() *http.Client {
+	if p.HTTPClient != nil {
+		return p.HTTPClient
+	}
+	return http.DefaultClient
+}
+
+// Metadata returns provider metadata fetched from the discovery endpoint of
+// the issuer. Failed discovery is retried on the next call.
+func (p *Provider) Metadata(ctx context.Context) (*Metadata, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.metadata != nil {
+		return p.metadata, nil
+	}
+
+	discoveryUrl := strings.TrimSuffix(p.Issuer, "/") + "/.well-known/openid-configuration"
+	var metadata Metadata
+	if err := p.getJSON(ctx, discoveryUrl, &metadata); err != nil {
+		return nil, fmt.Errorf("discovery failed: %s", err)
+	}
+	if metadata.Issuer != p.Issuer {
+		return nil, fmt.Errorf("discovery failed: issuer '%s' doesn't match '%s'", metadata.Issuer, p.Issuer)
+	}
+	if metadata.AuthorizationEndpoint == "" || metadata.TokenEndpoint == "" || metadata.JWKSURI == "" {
+		return nil, errors.New("discovery failed: required endpoints are missing")
+	}
+
+	p.metadata = &metadata
+	p.keys = &keySet{url: metadata.JWKSURI, fetch: p.getJSON}
+	return p.metadata, nil
+}
+
+// Authorization contains values of the authorization request, which have to
+// be kept until the callback
+type Authorization struct {
+	Url      string
+	State    string
+	Nonce    string
+	Verifier string
+}
+
+// Authorize creates authorization request redirecting back to redirectUrl
+func (p *Provider) Authorize(ctx context.Context, redirectUrl string) (*Authorization, error) {
+	metadata, err := p.Metadata(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	auth := &Authorization{
+		State:    randomString(),
+		Nonce:    randomString(),
+		Verifier: randomString() + randomString(),
+	}
+	challenge := sha256.Sum256([]byte(auth.Verifier))
+
+	query := url.Values{
+		"response_type":         {"code"},
+		"client_id":             {p.ClientId},
+		"redirect_uri":          {redirectUrl},
+		"scope":                 {p.scope()},
+		"state":                 {auth.State},
+		"nonce":                 {auth.Nonce},
+		"code_challenge":        {base64.RawURLEncoding.EncodeToString(challenge[:])},
+		"code_challenge_method": {"S256"},
+	}
+	separator := "?"
+	if strings.Contains(metadata.AuthorizationEndpoint, "?") {
+		separator = "&"
+	}
+	auth.Url = metadata.AuthorizationEndpoint + separator + query.Encode()
+	return auth, nil
+}
+
+func (p *Provider) scope() string {
+	scopes := []string{"openid"}
+	for _, scope := range p.Scopes {
+		if scope != "openid" {
+			scopes = append(scopes, scope)
+		}
+	}
+	return strings.Join(scopes, " ")
+}
+
+// Exchange redeems authorization code and returns the verified ID token
+func (p *Provider) Exchange(ctx context.Context, code string, redirectUrl string, auth *Authorization) (*IDToken, error) {
+	metadata, err := p.Metadata(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	form := url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {code},
+		"redirect_uri":  {redirectUrl},
+		"code_verifier": {auth.Verifier},
+	}
+	basicAuth := p.ClientSecret != "" && p.supportsBasicAuth(metadata)
+	if !basicAuth {
+		form.Set("client_id", p.ClientId)
+		if p.ClientSecret != "" {
+			form.Set("client_secret", p.ClientSecret)
+		}
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, metadata.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	if basicAuth {
+		req.SetBasicAuth(url.QueryEscape(p.ClientId), url.QueryEscape(p.ClientSecret))
+	}
+
+	res, err := p.client().Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("token request failed: %s", err)
+	}
+	defer res.Body.Close()
+
+	var token struct {
+		IDToken          string `json:"id_token"`
+		Error            string `json:"error"`
+		ErrorDescription string `json:"error_description"`
+	}
+	if err := json.NewDecoder(io.LimitReader(res.Body, maxResponseSize)).Decode(&token); err != nil {
+		return nil, fmt.Errorf("token request failed with status %v", res.StatusCode)
+	}
+	if token.Error != "" {
+		return nil, fmt.Errorf("token request failed: %s %s", token.Error, token.ErrorDescription)
+	}
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("token request failed with status %v", res.StatusCode)
+	}
+	if token.IDToken == "" {
+		return nil, errors.New("token response doesn't contain id token")
+	}
+
+	return p.Verify(ctx, token.IDToken, auth.Nonce)
+}
+
+// supportsBasicAuth reports whether client credentials are sent using basic
+// auth, which is the default method when provider doesn't list any
+func (p *Provider) supportsBasicAuth(metadata *Metadata) bool {
+	if len(metadata.TokenAuthMethods) == 0 {
+		return true
+	}
+	for _, method := range metadata.TokenAuthMethods {
+		if method == "client_secret_basic" {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *Provider) getJSON(ctx context.Context, url string, v interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+
+	res, err := p.client().Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s responded with status %v", url, res.StatusCode)
+	}
+	return json.NewDecoder(io.LimitReader(res.Body, maxResponseSize)).Decode(v)
+}
+
+func randomString() string {
+	b := make([]byte, 24)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	return base64.RawURLEncoding.EncodeToString(b)
+}
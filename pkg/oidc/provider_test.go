@@ -0,0 +1,335 @@
+package oidc
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt"
+)
+
+const testClientId = "client"
+const testRedirectUrl = "http://app.test/login/oidc/mock/callback"
+
+// mockServer is an OpenID Connect provider serving discovery, JWKS and token
+// endpoints. ID tokens are issued for any code registered by authorize.
+type mockServer struct {
+	*httptest.Server
+	t *testing.T
+
+	mu          sync.Mutex
+	keys        map[string]*rsa.PrivateKey
+	signingKid  string
+	jwksFetches int
+	codes       map[string]mockCode
+
+	// claims modifies claims of the issued tokens
+	claims func(claims jwt.MapClaims)
+	// sign overrides signing of the issued tokens
+	sign func(claims jwt.MapClaims) string
+}
+
+type mockCode struct {
+	challenge string
+	nonce     string
+}
+
+func newMockServer(t *testing.T) *mockServer {
+	s := &mockServer{t: t, keys: map[string]*rsa.PrivateKey{}, codes: map[string]mockCode{}}
+	s.rotate("k1")
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", s.discovery)
+	mux.HandleFunc("/jwks", s.jwks)
+	mux.HandleFunc("/token", s.token)
+	s.Server = httptest.NewServer(mux)
+	t.Cleanup(s.Close)
+	return s
+}
+
+// rotate publishes a new key and signs tokens using it
+func (s *mockServer) rotate(kid string) *rsa.PrivateKey {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		s.t.Fatal(err)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.keys = map[string]*rsa.PrivateKey{kid: key}
+	s.signingKid = kid
+	return key
+}
+
+func (s *mockServer) provider() *Provider {
+	return NewProvider(Config{
+		Name:         "mock",
+		Issuer:       s.URL,
+		ClientId:     testClientId,
+		ClientSecret: "secret",
+		HTTPClient:   s.Client(),
+	})
+}
+
+func (s *mockServer) discovery(w http.ResponseWriter, r *http.Request) {
+	json.NewEncoder(w).Encode(Metadata{
+		Issuer:                s.URL,
+		AuthorizationEndpoint: s.URL + "/authorize",
+		TokenEndpoint:         s.URL + "/token",
+		JWKSURI:               s.URL + "/jwks",
+	})
+}
+
+func (s *mockServer) jwks(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.jwksFetches++
+
+	var keys []jsonWebKey
+	for kid, key := range s.keys {
+		keys = append(keys, jsonWebKey{
+			Kty: "RSA",
+			Kid: kid,
+			Use: "sig",
+			N:   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+			E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+		})
+	}
+	json.NewEncoder(w).Encode(map[string]interface{}{"keys": keys})
+}
+
+func (s *mockServer) token(w http.ResponseWriter, r *http.Request) {
+	if id, secret, ok := r.BasicAuth(); !ok || id != testClientId || secret != "secret" {
+		w.WriteHeader(http.StatusUnauthorized)
+		json.NewEncoder(w).Encode(map[string]string{"error": "invalid_client"})
+		return
+	}
+
+	s.mu.Lock()
+	code, ok := s.codes[r.FormValue("code")]
+	delete(s.codes, r.FormValue("code"))
+	s.mu.Unlock()
+
+	challenge := sha256.Sum256([]byte(r.FormValue("code_verifier")))
+	if !ok || base64.RawURLEncoding.EncodeToString(challenge[:]) != code.challenge || r.FormValue("redirect_uri") != testRedirectUrl {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "invalid_grant"})
+		return
+	}
+
+	now := time.Now()
+	claims := jwt.MapClaims{
+		"iss":            s.URL,
+		"sub":            "user-1",
+		"aud":            testClientId,
+		"exp":            now.Add(time.Hour).Unix(),
+		"iat":            now.Unix(),
+		"nonce":          code.nonce,
+		"email":          "user@example.com",
+		"email_verified": "true",
+	}
+	if s.claims != nil {
+		s.claims(claims)
+	}
+
+	var idToken string
+	if s.sign != nil {
+		idToken = s.sign(claims)
+	} else {
+		s.mu.Lock()
+		token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+		token.Header["kid"] = s.signingKid
+		var err error
+		idToken, err = token.SignedString(s.keys[s.signingKid])
+		s.mu.Unlock()
+		if err != nil {
+			s.t.Fatal(err)
+		}
+	}
+	json.NewEncoder(w).Encode(map[string]string{"id_token": idToken, "token_type": "Bearer"})
+}
+
+// authorize starts authorization and registers code for the request
+func (s *mockServer) authorize(t *testing.T, p *Provider) *Authorization {
+	t.Helper()
+	auth, err := p.Authorize(context.Background(), testRedirectUrl)
+	if err != nil {
+		t.Fatalf("authorize failed: %s", err)
+	}
+
+	u, err := url.Parse(auth.Url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	query := u.Query()
+	if u.Path != "/authorize" || query.Get("client_id") != testClientId || query.Get("state") != auth.State || query.Get("code_challenge_method") != "S256" {
+		t.Fatalf("unexpected authorization url %s", auth.Url)
+	}
+
+	s.mu.Lock()
+	s.codes["code"] = mockCode{challenge: query.Get("code_challenge"), nonce: query.Get("nonce")}
+	s.mu.Unlock()
+	return auth
+}
+
+func (s *mockServer) exchange(t *testing.T, p *Provider) (*IDToken, error) {
+	t.Helper()
+	auth := s.authorize(t, p)
+	return p.Exchange(context.Background(), "code", testRedirectUrl, auth)
+}
+
+func TestExchange(t *testing.T) {
+	s := newMockServer(t)
+	token, err := s.exchange(t, s.provider())
+	if err != nil {
+		t.Fatalf("exchange failed: %s", err)
+	}
+	if token.Issuer != s.URL || token.Subject != "user-1" || token.Email != "user@example.com" || !token.EmailVerified {
+		t.Errorf("unexpected token %+v", token)
+	}
+}
+
+func TestExchangeRejectsInvalidVerifier(t *testing.T) {
+	s := newMockServer(t)
+	p := s.provider()
+	auth := s.authorize(t, p)
+	auth.Verifier = "wrong"
+	if _, err := p.Exchange(context.Background(), "code", testRedirectUrl, auth); err == nil {
+		t.Error("exchange succeeded with invalid code verifier")
+	}
+}
+
+func TestExchangeRejectsInvalidClaims(t *testing.T) {
+	tests := []struct {
+		name   string
+		claims func(claims jwt.MapClaims)
+	}{
+		{"nonce", func(claims jwt.MapClaims) { claims["nonce"] = "other" }},
+		{"missing nonce", func(claims jwt.MapClaims) { delete(claims, "nonce") }},
+		{"audience", func(claims jwt.MapClaims) { claims["aud"] = "other" }},
+		{"audience without authorized party", func(claims jwt.MapClaims) { claims["aud"] = []string{"other", testClientId} }},
+		{"authorized party", func(claims jwt.MapClaims) {
+			claims["aud"] = []string{"other", testClientId}
+			claims["azp"] = "other"
+		}},
+		{"issuer", func(claims jwt.MapClaims) { claims["iss"] = "https://evil.test" }},
+		{"subject", func(claims jwt.MapClaims) { delete(claims, "sub") }},
+		{"expired", func(claims jwt.MapClaims) { claims["exp"] = time.Now().Add(-time.Hour).Unix() }},
+		{"issued in future", func(claims jwt.MapClaims) { claims["iat"] = time.Now().Add(time.Hour).Unix() }},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			s := newMockServer(t)
+			s.claims = test.claims
+			if _, err := s.exchange(t, s.provider()); err == nil {
+				t.Error("exchange succeeded")
+			}
+		})
+	}
+}
+
+func TestExchangeAcceptsAuthorizedParty(t *testing.T) {
+	s := newMockServer(t)
+	s.claims = func(claims jwt.MapClaims) {
+		claims["aud"] = []string{"other", testClientId}
+		claims["azp"] = testClientId
+	}
+	if _, err := s.exchange(t, s.provider()); err != nil {
+		t.Errorf("exchange failed: %s", err)
+	}
+}
+
+func TestExchangeRejectsSignatureDowngrade(t *testing.T) {
+	tests := []struct {
+		name string
+		sign func(s *mockServer, claims jwt.MapClaims) string
+	}{
+		{"none", func(s *mockServer, claims jwt.MapClaims) string {
+			token := jwt.NewWithClaims(jwt.SigningMethodNone, claims)
+			token.Header["kid"] = "k1"
+			signed, _ := token.SignedString(jwt.UnsafeAllowNoneSignatureType)
+			return signed
+		}},
+		{"HS256 with public key", func(s *mockServer, claims jwt.MapClaims) string {
+			// Classic confusion attack using the public key as HMAC secret
+			public, _ := x509.MarshalPKIXPublicKey(&s.keys["k1"].PublicKey)
+			secret := pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: public})
+			token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+			token.Header["kid"] = "k1"
+			signed, _ := token.SignedString(secret)
+			return signed
+		}},
+		{"unknown key", func(s *mockServer, claims jwt.MapClaims) string {
+			key, _ := rsa.GenerateKey(rand.Reader, 2048)
+			token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+			token.Header["kid"] = "k1"
+			signed, _ := token.SignedString(key)
+			return signed
+		}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			s := newMockServer(t)
+			s.sign = func(claims jwt.MapClaims) string { return test.sign(s, claims) }
+			if _, err := s.exchange(t, s.provider()); err == nil {
+				t.Error("exchange succeeded")
+			}
+		})
+	}
+}
+
+func TestKeyRotation(t *testing.T) {
+	s := newMockServer(t)
+	p := s.provider()
+	if _, err := s.exchange(t, p); err != nil {
+		t.Fatalf("exchange failed: %s", err)
+	}
+
+	// Keys aren't refetched again right after fetching them
+	s.rotate("k2")
+	if _, err := s.exchange(t, p); err == nil {
+		t.Error("exchange succeeded with unknown key before refetch interval")
+	}
+	if s.jwksFetches != 1 {
+		t.Errorf("keys are fetched %v times, want 1", s.jwksFetches)
+	}
+
+	// Unknown keys are fetched after the interval
+	p.keys.mu.Lock()
+	p.keys.fetchedAt = time.Now().Add(-keysRefetchInterval)
+	p.keys.mu.Unlock()
+	if _, err := s.exchange(t, p); err != nil {
+		t.Fatalf("exchange failed after key rotation: %s", err)
+	}
+	if s.jwksFetches != 2 {
+		t.Errorf("keys are fetched %v times, want 2", s.jwksFetches)
+	}
+
+	// Known keys are used without fetching
+	if _, err := s.exchange(t, p); err != nil {
+		t.Fatalf("exchange failed: %s", err)
+	}
+	if s.jwksFetches != 2 {
+		t.Errorf("keys are fetched %v times, want 2", s.jwksFetches)
+	}
+}
+
+func TestDiscoveryRejectsIssuerMismatch(t *testing.T) {
+	s := newMockServer(t)
+	p := NewProvider(Config{Name: "mock", Issuer: s.URL + "/", ClientId: testClientId, HTTPClient: s.Client()})
+	if _, err := p.Authorize(context.Background(), testRedirectUrl); err == nil || !strings.Contains(err.Error(), "issuer") {
+		t.Errorf("discovery didn't reject issuer mismatch: %v", err)
+	}
+}
@@ -0,0 +1,109 @@
+package opml
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"time"
+
+	"golang.org/x/net/html/charset"
+)
+
+// Document is an OPML 2.0 document, see http://opml.org/spec2.opml
+type Document struct {
+	XMLName  xml.Name  `xml:"opml"`
+	Version  string    `xml:"version,attr"`
+	Head     Head      `xml:"head"`
+	Outlines []Outline `xml:"body>outline"`
+}
+
+type Head struct {
+	Title       string `xml:"title,omitempty"`
+	DateCreated string `xml:"dateCreated,omitempty"`
+	OwnerName   string `xml:"ownerName,omitempty"`
+}
+
+// Outline is either a folder containing other outlines or a subscription
+// when XmlUrl is set
+type Outline struct {
+	Text     string    `xml:"text,attr"`
+	Title    string    `xml:"title,attr,omitempty"`
+	Type     string    `xml:"type,attr,omitempty"`
+	XmlUrl   string    `xml:"xmlUrl,attr,omitempty"`
+	HtmlUrl  string    `xml:"htmlUrl,attr,omitempty"`
+	Outlines []Outline `xml:"outline"`
+}
+
+// New creates a document with the given title
+func New(title string, now time.Time) *Document {
+	return &Document{
+		Version: "2.0",
+		Head: Head{
+			Title:       title,
+			DateCreated: now.UTC().Format(time.RFC1123Z),
+		},
+	}
+}
+
+// Folder creates an outline containing the given outlines
+func Folder(text string, outlines ...Outline) Outline {
+	return Outline{Text: text, Title: text, Outlines: outlines}
+}
+
+// Subscription creates a feed outline
+func Subscription(text string, xmlUrl string, htmlUrl string) Outline {
+	return Outline{
+		Text:    text,
+		Title:   text,
+		Type:    "rss",
+		XmlUrl:  xmlUrl,
+		HtmlUrl: htmlUrl,
+	}
+}
+
+// Name returns display name of the outline
+func (o *Outline) Name() string {
+	if o.Title != "" {
+		return o.Title
+	}
+	return o.Text
+}
+
+// IsSubscription reports whether the outline points to a feed
+func (o *Outline) IsSubscription() bool {
+	return o.XmlUrl != ""
+}
+
+// Subscriptions returns feed outlines nested under the outline at any depth
+func (o *Outline) Subscriptions() []Outline {
+	var result []Outline
+	for _, child := range o.Outlines {
+		if child.IsSubscription() {
+			result = append(result, child)
+		}
+		result = append(result, child.Subscriptions()...)
+	}
+	return result
+}
+
+// Parse reads OPML document, both 1.0 and 2.0 documents are accepted
+func Parse(r io.Reader) (*Document, error) {
+	var doc Document
+	decoder := xml.NewDecoder(r)
+	decoder.Strict = false
+	decoder.CharsetReader = charset.NewReaderLabel
+	if err := decoder.Decode(&doc); err != nil {
+		return nil, fmt.Errorf("failed to parse opml: %s", err)
+	}
+	return &doc, nil
+}
+
+// Write writes the document with xml header
+func (d *Document) Write(w io.Writer) error {
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "  ")
+	return encoder.Encode(d)
+}
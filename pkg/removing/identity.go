@@ -0,0 +1,5 @@
+package removing
+
+type IdentityRepository interface {
+	RemoveUserIdentity(userId string, provider string, subject string) error
+}
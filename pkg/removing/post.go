@@ -1,6 +1,19 @@
 package removing
 
+import "time"
+
+// Retention limits how long posts are kept, zero values mean unlimited
+type Retention struct {
+	MaxAgeDays int
+	MaxPosts   int
+}
+
 type PostRepository interface {
 	RemoveSourcePost(sourceId int, postId int) error
 	RemoveAllSourcePosts(sourceId int) error
+
+	// RemoveExpiredPosts removes posts exceeding retention limits of every
+	// feed including their source and returns number of removed posts.
+	// Defaults are used for feeds without custom limits.
+	RemoveExpiredPosts(defaults Retention, now time.Time) (int64, error)
 }
@@ -0,0 +1,6 @@
+package removing
+
+type SavedItemRepository interface {
+	RemoveSavedItem(userId string, itemId int) error
+	RemoveSavedPost(userId string, postId int) error
+}
@@ -0,0 +1,9 @@
+package removing
+
+type SessionRepository interface {
+	RemoveUserSession(userId string, id string) error
+
+	// RemoveOtherSessions removes every session of the user except the
+	// given one
+	RemoveOtherSessions(userId string, keepId string) error
+}
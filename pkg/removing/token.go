@@ -0,0 +1,5 @@
+package removing
+
+type TokenRepository interface {
+	RemoveUserToken(userId string, id int) error
+}
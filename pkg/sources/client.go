@@ -0,0 +1,62 @@
+package sources
+
+import (
+	"errors"
+	"net"
+	"net/http"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// ErrPrivateAddress is returned when source url points to a loopback,
+// private or link-local address
+var ErrPrivateAddress = errors.New("url points to a private network address")
+
+// Maximum duration of connecting to a server, requests are bound by their
+// context otherwise
+const dialTimeout = 10 * time.Second
+
+// publicClient is used for fetching sources and discovering feeds by default.
+// Urls are entered by users, so the server isn't let to reach services on its
+// own network.
+var publicClient = &http.Client{
+	Transport: &http.Transport{
+		DialContext: (&net.Dialer{
+			Timeout: dialTimeout,
+			Control: dialPublicOnly,
+		}).DialContext,
+		TLSHandshakeTimeout: dialTimeout,
+		MaxIdleConnsPerHost: 2,
+		IdleConnTimeout:     90 * time.Second,
+	},
+}
+
+// dialPublicOnly rejects connections to non-public addresses. Addresses are
+// checked after name resolution, so it covers redirects and hosts resolving
+// to private addresses.
+func dialPublicOnly(network, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	if ip := net.ParseIP(host); ip == nil || !isPublicIP(ip) {
+		return ErrPrivateAddress
+	}
+	return nil
+}
+
+// isPublicIP reports whether ip is a globally routable unicast address
+func isPublicIP(ip net.IP) bool {
+	return ip.IsGlobalUnicast() && !ip.IsPrivate() && !ip.IsLoopback() && !ip.IsLinkLocalUnicast()
+}
+
+// IsPrivateHost reports whether host is a non-public ip address or a
+// localhost name. Other names are checked once they're resolved on fetch.
+func IsPrivateHost(host string) bool {
+	if ip := net.ParseIP(host); ip != nil {
+		return !isPublicIP(ip)
+	}
+	host = strings.TrimSuffix(strings.ToLower(host), ".")
+	return host == "localhost" || strings.HasSuffix(host, ".localhost")
+}
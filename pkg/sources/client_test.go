@@ -0,0 +1,70 @@
+package sources
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestFeedResolverRejectsPrivateAddresses(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("server is reached: %s", r.URL)
+	}))
+	defer server.Close()
+
+	// Default client is used
+	_, err := NewFeedResolver(nil).Resolve(context.Background(), Request{Url: server.URL})
+	if !errors.Is(err, ErrPrivateAddress) {
+		t.Errorf("got %v, want %v", err, ErrPrivateAddress)
+	}
+}
+
+func TestIsPublicIP(t *testing.T) {
+	tests := []struct {
+		ip   string
+		want bool
+	}{
+		{"93.184.216.34", true},
+		{"2606:2800:220:1:248:1893:25c8:1946", true},
+		{"127.0.0.1", false},
+		{"::1", false},
+		{"10.1.2.3", false},
+		{"172.16.0.1", false},
+		{"192.168.1.1", false},
+		{"169.254.169.254", false},
+		{"fe80::1", false},
+		{"fd00::1", false},
+		{"0.0.0.0", false},
+		{"::ffff:127.0.0.1", false},
+		{"224.0.0.1", false},
+	}
+	for _, test := range tests {
+		if got := isPublicIP(net.ParseIP(test.ip)); got != test.want {
+			t.Errorf("isPublicIP(%s) = %v, want %v", test.ip, got, test.want)
+		}
+	}
+}
+
+func TestIsPrivateHost(t *testing.T) {
+	tests := []struct {
+		host string
+		want bool
+	}{
+		{"example.com", false},
+		{"93.184.216.34", false},
+		{"localhost", true},
+		{"LOCALHOST.", true},
+		{"app.localhost", true},
+		{"127.0.0.1", true},
+		{"::1", true},
+		{"169.254.169.254", true},
+	}
+	for _, test := range tests {
+		if got := IsPrivateHost(test.host); got != test.want {
+			t.Errorf("IsPrivateHost(%s) = %v, want %v", test.host, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,196 @@
+package sources
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mmcdole/gofeed"
+	"golang.org/x/net/html"
+)
+
+// Candidate is a feed discovered from a web page
+type Candidate struct {
+	Url   string
+	Title string
+}
+
+// Paths probed when a web page doesn't advertise its feeds
+var commonFeedPaths = []string{"/feed", "/rss", "/feed.xml", "/rss.xml", "/atom.xml", "/index.xml", "/feed.json"}
+
+// Feed types advertised using <link rel="alternate"> elements
+var feedMediaTypes = map[string]bool{
+	"application/rss+xml":   true,
+	"application/atom+xml":  true,
+	"application/feed+json": true,
+}
+
+// Maximum number of bytes read from discovered documents
+const maxDiscoveryBodySize = 5 << 20
+
+// Maximum duration of discovering feeds on a url including the probes, since
+// discovery runs while user waits for the response
+const discoveryTimeout = 10 * time.Second
+
+type Discoverer struct {
+	// Client is used for fetching documents, publicClient is used when nil
+	Client *http.Client
+}
+
+func (d *Discoverer) httpClient() *http.Client {
+	if d.Client != nil {
+		return d.Client
+	}
+	return publicClient
+}
+
+// Discover returns feeds found on the given url. If the url points to a feed
+// it is returned as the only candidate, otherwise feeds advertised by the web
+// page are returned. When page doesn't advertise any feed, common feed paths
+// of the website are probed concurrently.
+func (d *Discoverer) Discover(ctx context.Context, pageUrl string) ([]Candidate, error) {
+	ctx, cancel := context.WithTimeout(ctx, discoveryTimeout)
+	defer cancel()
+
+	body, base, err := d.fetch(ctx, pageUrl)
+	if err != nil {
+		return nil, err
+	}
+
+	// Url already points to a feed
+	if gofeed.DetectFeedType(bytes.NewReader(body)) != gofeed.FeedTypeUnknown {
+		return []Candidate{{Url: pageUrl}}, nil
+	}
+
+	if candidates := findFeedLinks(body, base); len(candidates) > 0 {
+		return candidates, nil
+	}
+
+	probeUrls := make([]string, len(commonFeedPaths))
+	found := make([]bool, len(commonFeedPaths))
+	var wg sync.WaitGroup
+	for i, path := range commonFeedPaths {
+		probeUrls[i] = base.ResolveReference(&url.URL{Path: path}).String()
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			body, _, err := d.fetch(ctx, probeUrls[i])
+			found[i] = err == nil && gofeed.DetectFeedType(bytes.NewReader(body)) != gofeed.FeedTypeUnknown
+		}(i)
+	}
+	wg.Wait()
+
+	// Keep order of the paths
+	var candidates []Candidate
+	for i, probeUrl := range probeUrls {
+		if found[i] {
+			candidates = append(candidates, Candidate{Url: probeUrl})
+		}
+	}
+
+	return candidates, nil
+}
+
+// fetch returns document body and the url it was served from
+func (d *Discoverer) fetch(ctx context.Context, documentUrl string) ([]byte, *url.URL, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, documentUrl, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	req.Header.Set("User-Agent", "myfeed/1.0")
+
+	resp, err := d.httpClient().Do(req)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, nil, &HTTPError{
+			StatusCode: resp.StatusCode,
+			Status:     resp.Status,
+		}
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxDiscoveryBodySize))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return body, resp.Request.URL, nil
+}
+
+// findFeedLinks returns feeds advertised by <link rel="alternate"> elements
+// of the given html document
+func findFeedLinks(body []byte, base *url.URL) []Candidate {
+	var candidates []Candidate
+	seen := map[string]bool{}
+
+	tokenizer := html.NewTokenizer(bytes.NewReader(body))
+	for {
+		switch tokenizer.Next() {
+		case html.ErrorToken:
+			return candidates
+
+		case html.StartTagToken, html.SelfClosingTagToken:
+			token := tokenizer.Token()
+
+			switch token.Data {
+			case "base":
+				if href, ok := attr(token, "href"); ok {
+					if ref, err := base.Parse(href); err == nil {
+						base = ref
+					}
+				}
+
+			case "link":
+				rel, _ := attr(token, "rel")
+				mediaType, _ := attr(token, "type")
+				href, _ := attr(token, "href")
+				if !hasToken(rel, "alternate") || !feedMediaTypes[strings.ToLower(strings.TrimSpace(mediaType))] || href == "" {
+					continue
+				}
+
+				ref, err := base.Parse(href)
+				if err != nil || seen[ref.String()] {
+					continue
+				}
+				seen[ref.String()] = true
+
+				title, _ := attr(token, "title")
+				candidates = append(candidates, Candidate{
+					Url:   ref.String(),
+					Title: title,
+				})
+
+			case "body":
+				// Feed links are only expected in document head
+				return candidates
+			}
+		}
+	}
+}
+
+func attr(token html.Token, key string) (string, bool) {
+	for _, a := range token.Attr {
+		if a.Key == key {
+			return a.Val, true
+		}
+	}
+	return "", false
+}
+
+// hasToken reports whether space separated list contains given token
+func hasToken(list string, token string) bool {
+	for _, item := range strings.Fields(list) {
+		if strings.EqualFold(item, token) {
+			return true
+		}
+	}
+	return false
+}
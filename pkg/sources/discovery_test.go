@@ -0,0 +1,56 @@
+package sources
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func TestDiscoverProbesCommonPaths(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte(`<html><head><title>Blog</title></head><body></body></html>`))
+	})
+	mux.HandleFunc("/atom.xml", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(testRSS))
+	})
+	mux.HandleFunc("/rss", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(testRSS))
+	})
+	mux.HandleFunc("/feed", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`<html></html>`))
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	d := &Discoverer{Client: server.Client()}
+	candidates, err := d.Discover(context.Background(), server.URL)
+	if err != nil {
+		t.Fatalf("discover failed: %s", err)
+	}
+
+	want := []Candidate{{Url: server.URL + "/rss"}, {Url: server.URL + "/atom.xml"}}
+	if !reflect.DeepEqual(candidates, want) {
+		t.Errorf("got %v, want %v", candidates, want)
+	}
+}
+
+func TestDiscoverRejectsPrivateAddresses(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("server is reached: %s", r.URL)
+	}))
+	defer server.Close()
+
+	// Default client is used
+	d := &Discoverer{}
+	if _, err := d.Discover(context.Background(), server.URL); !errors.Is(err, ErrPrivateAddress) {
+		t.Errorf("got %v, want %v", err, ErrPrivateAddress)
+	}
+}
@@ -1,7 +1,12 @@
 package sources
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"net/url"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/themisir/myfeed/pkg/listing"
@@ -9,17 +14,31 @@ import (
 
 	"github.com/themisir/myfeed/pkg/adding"
 	"github.com/themisir/myfeed/pkg/models"
+	"github.com/themisir/myfeed/pkg/removing"
 	"github.com/themisir/myfeed/pkg/updating"
 )
 
 func NewManager(sourceRepository models.SourceRepository, postRepository models.PostRepository, feedUpdating updating.FeedRepository, logger log.Logger) *Manager {
+	ctx, cancel := context.WithCancel(context.Background())
 	return &Manager{
+		ctx:              ctx,
+		cancel:           cancel,
 		sourceRepository: sourceRepository,
 		postRepository:   postRepository,
 		feedUpdating:     feedUpdating,
-		resolver:         &resolver{},
-		queue:            make(chan sourceQueueEntry, 32),
+		discoverer:       &Discoverer{},
 		logger:           logger,
+
+		Workers:         4,
+		QueueCapacity:   1024,
+		HostConcurrency: 2,
+		Resolvers:       NewRegistry(NewFeedResolver(nil)),
+		Scheduler: Scheduler{
+			MinInterval:      10 * time.Minute,
+			MaxInterval:      24 * time.Hour,
+			FailureThreshold: 5,
+			MaxBackoff:       7 * 24 * time.Hour,
+		},
 	}
 }
 
@@ -29,23 +48,68 @@ type Manager struct {
 	feedUpdating     updating.FeedRepository
 	logger           log.Logger
 
-	resolver Resolver
+	discoverer *Discoverer
+
+	queue *sourceQueue
+
+	// ctx is cancelled when manager is stopped
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
 
-	queue chan sourceQueueEntry
+	// Number of sources fetched concurrently, maximum number of sources
+	// waiting to be fetched and maximum number of concurrent fetches from a
+	// single host
+	Workers         int
+	QueueCapacity   int
+	HostConcurrency int
+
+	// Resolvers used for fetching sources, custom resolvers can be
+	// registered before starting the manager
+	Resolvers *Registry
+	Scheduler Scheduler
+
+	// Retention limits used for feeds without custom limits, posts are
+	// kept forever by default
+	Retention removing.Retention
 }
 
-type sourceQueueEntry struct {
-	id  int
-	url string
+// Disabled reports whether the source is disabled due to consecutive failures
+func (m *Manager) Disabled(source listing.Source) bool {
+	return m.Scheduler.Disabled(source.FailureCount())
 }
 
-func (m *Manager) UpdateFeedSources(feedId int, sourceUrls ...string) error {
-	sourceIds := make([]int, len(sourceUrls))
+// Discover returns feeds found on the given url
+func (m *Manager) Discover(ctx context.Context, url string) ([]Candidate, error) {
+	// Sources handled by custom resolvers aren't web pages
+	if m.Resolvers.Lookup(url) != nil {
+		return []Candidate{{Url: url}}, nil
+	}
+	return m.discoverer.Discover(ctx, url)
+}
+
+// FeedSource is a source url as it is configured within a feed
+type FeedSource struct {
+	Url string
+	// Title overrides the source title within the feed when not empty
+	Title string
+}
+
+func (m *Manager) UpdateFeedSources(feedId int, sources ...FeedSource) error {
+	feedSources := make([]updating.FeedSource, 0, len(sources))
+	seen := make(map[string]bool, len(sources))
+
+	for _, feedSource := range sources {
+		url := feedSource.Url
+		if seen[url] {
+			continue
+		}
+		seen[url] = true
 
-	for i, url := range sourceUrls {
+		var sourceId int
 		source, _ := m.sourceRepository.FindSourceByUrl(url)
 		if source != nil {
-			sourceIds[i] = source.Id()
+			sourceId = source.Id()
 		} else {
 			// Create a new source
 			source, err := m.sourceRepository.AddSource(adding.SourceData{
@@ -57,97 +121,320 @@ func (m *Manager) UpdateFeedSources(feedId int, sourceUrls ...string) error {
 			}
 
 			// Enqueue source for processing
-			go m.enqueue(source)
+			m.enqueue(sourceQueueEntry{
+				id:  source.Id(),
+				url: source.Url(),
+			})
 
-			sourceIds[i] = source.Id()
+			sourceId = source.Id()
 		}
+
+		feedSources = append(feedSources, updating.FeedSource{
+			SourceId: sourceId,
+			Title:    strings.TrimSpace(feedSource.Title),
+		})
 	}
 
-	return m.feedUpdating.UpdateFeedSources(feedId, sourceIds...)
+	return m.feedUpdating.UpdateFeedSources(feedId, feedSources...)
 }
 
-func (m *Manager) Start() error {
+// Start starts processing sources until the given context is cancelled or
+// Stop is called
+func (m *Manager) Start(ctx context.Context) error {
+	m.ctx, m.cancel = context.WithCancel(ctx)
+	m.queue = newSourceQueue(m.QueueCapacity, m.HostConcurrency)
+
+	// Release workers once stopped
+	go func() {
+		<-m.ctx.Done()
+		m.queue.Close()
+	}()
+
 	// Start periodic timer
+	m.wg.Add(1)
 	go m.runTimer()
 
-	// Create 4 worker goroutine for processing sources
-	for i := 0; i < 4; i++ {
+	// Create worker goroutines for processing sources
+	for i := 0; i < m.Workers; i++ {
+		m.wg.Add(1)
 		go m.processSources()
 	}
 
-	return m.enqueueExistingSources()
+	return m.enqueueDueSources()
+}
+
+// Stop abandons queued sources, cancels in-flight fetches and waits for the
+// workers to finish. Abandoned sources remain due and are processed on the
+// next start.
+func (m *Manager) Stop() {
+	m.cancel()
+	m.wg.Wait()
 }
 
-func (m *Manager) enqueueExistingSources() error {
-	sources, err := m.sourceRepository.GetSources()
+func (m *Manager) enqueueDueSources() error {
+	sources, err := m.sourceRepository.GetDueSources(time.Now().UTC())
 	if err != nil {
 		return err
 	}
 
 	for _, source := range sources {
-		go m.enqueue(source)
+		m.enqueue(sourceQueueEntry{
+			id:           source.Id(),
+			url:          source.Url(),
+			etag:         source.ETag(),
+			lastModified: source.LastModified(),
+		})
 	}
+
+	pending, inFlight := m.QueueDepth()
+	m.logger.Debugf("%v sources are due, %v queued and %v being fetched", len(sources), pending, inFlight)
 	return nil
 }
 
-func (m *Manager) enqueue(source listing.Source) {
-	m.queue <- sourceQueueEntry{
-		id:  source.Id(),
-		url: source.Url(),
+// QueueDepth returns number of sources waiting to be fetched and number of
+// sources being fetched
+func (m *Manager) QueueDepth() (pending int, inFlight int) {
+	if m.queue == nil {
+		return 0, 0
+	}
+	return m.queue.Len()
+}
+
+// Delay of fetching sources that couldn't be queued because the queue is full
+const queueFullDelay = 5 * time.Minute
+
+func (m *Manager) enqueue(entry sourceQueueEntry) {
+	if m.queue == nil {
+		return
+	}
+	if err := m.queue.Push(entry); err != nil {
+		// Postpone the source, so the next runs don't retry it before
+		// the queue drains
+		m.logger.Warnf("failed to queue source %v: %s, retrying in %v", entry.id, err, queueFullDelay)
+		m.schedule(entry.id, time.Now().UTC().Add(queueFullDelay))
 	}
 }
 
 func (m *Manager) processSources() {
+	defer m.wg.Done()
+
 	for {
-		source := <-m.queue
+		source, ok := m.queue.Pop()
+		if !ok {
+			return
+		}
+
+		m.processSource(source)
+		m.queue.Done(source)
+	}
+}
+
+func (m *Manager) processSource(source sourceQueueEntry) {
+	now := time.Now().UTC()
+
+	// Resolve source
+	resolved, err := m.Resolvers.Resolve(m.ctx, Request{
+		Url:          source.url,
+		ETag:         source.etag,
+		LastModified: source.lastModified,
+	})
+	if err != nil {
+		if m.ctx.Err() != nil {
+			// Fetch is cancelled because manager is stopping
+			return
+		}
+
+		m.logger.Errorf("failed to process source %v on '%s': %s", source.id, source.url, err)
+
+		var status int
+		var httpErr *HTTPError
+		if errors.As(err, &httpErr) {
+			status = httpErr.StatusCode
+		}
+
+		failures, recordErr := m.sourceRepository.RecordSourceFailure(source.id, status, err.Error())
+		if recordErr != nil {
+			m.logger.Errorf("failed to record failure of source %v: %s", source.id, recordErr)
+		}
+
+		m.schedule(source.id, m.Scheduler.NextAfterError(now, err, failures))
+		return
+	}
+
+	if err := m.sourceRepository.RecordSourceSuccess(source.id, resolved.StatusCode, now); err != nil {
+		m.logger.Errorf("failed to record success of source %v: %s", source.id, err)
+	}
+
+	// Nothing has changed since the last fetch
+	if resolved.NotModified {
+		m.schedule(source.id, m.Scheduler.Next(now, resolved, m.publishedTimes(source.id)))
+		return
+	}
 
-		// Resolve source
-		resolved, err := m.resolver.Resolve(source.url)
-		if err != nil {
-			m.logger.Errorf("failed to process source %v on '%s': %s", source.id, source.url, err)
+	// Map resolved items into posts
+	posts := make([]adding.PostData, 0, len(resolved.Items))
+	published := make([]time.Time, 0, len(resolved.Items))
+	keys := make(map[string]bool, len(resolved.Items))
+	for _, item := range resolved.Items {
+		key := item.Key()
+		if keys[key] {
+			// Skip duplicate items
 			continue
 		}
+		keys[key] = true
 
-		// Update source details
-		_ = m.sourceRepository.UpdateSource(source.id, updating.Source{
-			Title: resolved.Title,
+		posts = append(posts, adding.PostData{
+			SourceId:    source.id,
+			Guid:        key,
+			Title:       PlainText(item.Title),
+			Description: SanitizeHTML(item.Description, itemBase(item.Url)),
+			Url:         item.Url,
+			Author:      PlainText(item.Author),
+			Categories:  categories(item.Categories),
+			PublishedAt: item.PublishedAt,
+			UpdatedAt:   item.UpdatedAt,
 		})
 
-		// Map resolved items into posts
-		posts := make([]adding.PostData, len(resolved.Items))
-		for i, item := range resolved.Items {
-			posts[i] = adding.PostData{
-				SourceId:    source.id,
-				Title:       item.Title,
-				Description: item.Description,
-				Url:         item.Url,
-				PublishedAt: item.PublishedAt,
-				UpdatedAt:   item.UpdatedAt,
-			}
+		if t := firstTime(item.PublishedAt, item.UpdatedAt); t != nil {
+			published = append(published, *t)
+		}
+	}
+
+	// Update cached posts. Cache validators are kept when posts can't be
+	// stored, so the next fetch doesn't skip them as not modified.
+	etag, lastModified := resolved.ETag, resolved.LastModified
+	if err := m.postRepository.UpsertPosts(posts...); err != nil {
+		m.logger.Errorf("failed to update posts of source %v: %s", source.id, err)
+		etag, lastModified = source.etag, source.lastModified
+	}
+
+	// Update source details
+	if err := m.sourceRepository.UpdateSource(source.id, updating.Source{
+		Title:        PlainText(resolved.Title),
+		Description:  PlainText(resolved.Description),
+		SiteUrl:      resolved.SiteUrl,
+		ImageUrl:     resolved.ImageUrl,
+		Diagnostics:  diagnostics(resolved.Skipped),
+		ETag:         etag,
+		LastModified: lastModified,
+		ItemCount:    len(resolved.Items),
+	}); err != nil {
+		m.logger.Errorf("failed to update source %v: %s", source.id, err)
+	}
+
+	m.schedule(source.id, m.Scheduler.Next(now, resolved, published))
+}
+
+// itemBase returns url used for resolving relative links in item contents
+func itemBase(itemUrl string) *url.URL {
+	base, err := url.Parse(itemUrl)
+	if err != nil {
+		return nil
+	}
+	return base
+}
+
+// publishedTimes returns publish times of the stored source posts
+func (m *Manager) publishedTimes(sourceId int) []time.Time {
+	posts, err := m.postRepository.GetSourcePosts(sourceId, listing.Page{Limit: recentPosts})
+	if err != nil {
+		m.logger.Errorf("failed to get posts of source %v: %s", sourceId, err)
+		return nil
+	}
+
+	published := make([]time.Time, 0, len(posts))
+	for _, post := range posts {
+		if t := firstTime(post.PublishedAt(), post.UpdatedAt()); t != nil {
+			published = append(published, *t)
 		}
+	}
+	return published
+}
 
-		// Update cached posts
-		_ = m.postRepository.RemoveAllSourcePosts(source.id)
-		_ = m.postRepository.AddManyPosts(posts...)
+func (m *Manager) schedule(sourceId int, nextFetchAt time.Time) {
+	if err := m.sourceRepository.ScheduleSource(sourceId, nextFetchAt); err != nil {
+		m.logger.Errorf("failed to schedule source %v: %s", sourceId, err)
 	}
 }
 
 func (m *Manager) runTimer() {
-	t := time.NewTicker(10 * time.Minute)
-	defer t.Stop()
+	defer m.wg.Done()
+
+	schedule := time.NewTicker(time.Minute)
+	defer schedule.Stop()
+
+	cleanup := time.NewTicker(10 * time.Minute)
+	defer cleanup.Stop()
+
+	retention := time.NewTicker(time.Hour)
+	defer retention.Stop()
 
 	for {
 		select {
-		case <-t.C:
+		case <-m.ctx.Done():
+			return
+
+		case <-cleanup.C:
 			// Clean up
 			if err := m.sourceRepository.RemoveEmptySources(); err != nil {
 				m.logger.Errorf("failed to clean up unused sources: %s", err)
 			}
 
+		case <-retention.C:
+			// Remove expired posts
+			removed, err := m.postRepository.RemoveExpiredPosts(m.Retention, time.Now().UTC())
+			if err != nil {
+				m.logger.Errorf("failed to remove expired posts: %s", err)
+			} else if removed > 0 {
+				m.logger.Debugf("removed %v expired posts", removed)
+			}
+
+		case <-schedule.C:
 			// Update
-			if err := m.enqueueExistingSources(); err != nil {
-				m.logger.Errorf("failed to enqueue existing sources: %s", err)
+			if err := m.enqueueDueSources(); err != nil {
+				m.logger.Errorf("failed to enqueue due sources: %s", err)
 			}
 		}
 	}
 }
+
+// categories returns plain text item categories without duplicates
+func categories(values []string) []string {
+	result := make([]string, 0, len(values))
+	seen := make(map[string]bool, len(values))
+	for _, value := range values {
+		value = PlainText(value)
+		if value == "" || seen[value] {
+			continue
+		}
+		seen[value] = true
+		result = append(result, value)
+	}
+	return result
+}
+
+// Maximum number of skipped items listed in source diagnostics
+const maxDiagnosticsLines = 20
+
+// diagnostics summarizes items skipped while resolving the source
+func diagnostics(skipped []SkippedItem) string {
+	lines := make([]string, 0, len(skipped))
+	for i, item := range skipped {
+		if i == maxDiagnosticsLines {
+			lines = append(lines, fmt.Sprintf("and %v more items skipped", len(skipped)-i))
+			break
+		}
+		name := firstNonEmpty(strings.TrimSpace(item.Title), strings.TrimSpace(item.Link), "untitled item")
+		lines = append(lines, fmt.Sprintf("skipped '%s': %s", name, item.Reason))
+	}
+	return strings.Join(lines, "\n")
+}
+
+func firstTime(times ...*time.Time) *time.Time {
+	for _, t := range times {
+		if t != nil {
+			return t
+		}
+	}
+	return nil
+}
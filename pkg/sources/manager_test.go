@@ -0,0 +1,177 @@
+package sources
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/themisir/myfeed/pkg/adding"
+	"github.com/themisir/myfeed/pkg/listing"
+	"github.com/themisir/myfeed/pkg/models"
+	"github.com/themisir/myfeed/pkg/updating"
+)
+
+// discardLogger drops every message
+type discardLogger struct{}
+
+func (discardLogger) Debug(i ...interface{})                    {}
+func (discardLogger) Debugf(format string, args ...interface{}) {}
+func (discardLogger) Info(i ...interface{})                     {}
+func (discardLogger) Infof(format string, args ...interface{})  {}
+func (discardLogger) Warn(i ...interface{})                     {}
+func (discardLogger) Warnf(format string, args ...interface{})  {}
+func (discardLogger) Error(i ...interface{})                    {}
+func (discardLogger) Errorf(format string, args ...interface{}) {}
+
+// staticResolver resolves every source to the same result
+type staticResolver struct {
+	resolved *ResolvedSource
+	err      error
+}
+
+func (r staticResolver) Resolve(ctx context.Context, req Request) (*ResolvedSource, error) {
+	return r.resolved, r.err
+}
+
+// fakeSources records writes of the manager, other methods aren't used
+type fakeSources struct {
+	models.SourceRepository
+	calls []string
+
+	scheduled time.Time
+	succeeded time.Time
+	updated   *updating.Source
+}
+
+func (r *fakeSources) ScheduleSource(sourceId int, nextFetchAt time.Time) error {
+	r.calls = append(r.calls, "schedule")
+	r.scheduled = nextFetchAt
+	return nil
+}
+
+func (r *fakeSources) RecordSourceSuccess(sourceId int, status int, at time.Time) error {
+	r.calls = append(r.calls, "success")
+	r.succeeded = at
+	return nil
+}
+
+func (r *fakeSources) RecordSourceFailure(sourceId int, status int, message string) (int, error) {
+	r.calls = append(r.calls, "failure")
+	return 1, nil
+}
+
+func (r *fakeSources) UpdateSource(sourceId int, data updating.Source) error {
+	r.calls = append(r.calls, "update")
+	r.updated = &data
+	return nil
+}
+
+// fakePosts records upserted posts, other methods aren't used
+type fakePosts struct {
+	models.PostRepository
+	sources  *fakeSources
+	upserted []adding.PostData
+	err      error
+}
+
+func (r *fakePosts) UpsertPosts(items ...adding.PostData) error {
+	r.sources.calls = append(r.sources.calls, "upsert")
+	if r.err != nil {
+		return r.err
+	}
+	r.upserted = append(r.upserted, items...)
+	return nil
+}
+
+func (r *fakePosts) GetSourcePosts(sourceId int, page listing.Page) ([]listing.Post, error) {
+	return nil, nil
+}
+
+func newTestManager(resolver Resolver) (*Manager, *fakeSources, *fakePosts) {
+	sources := &fakeSources{}
+	posts := &fakePosts{sources: sources}
+	m := NewManager(sources, posts, nil, discardLogger{})
+	m.Resolvers = NewRegistry(resolver)
+	return m, sources, posts
+}
+
+func TestProcessSourceRecordsUTCTimes(t *testing.T) {
+	// Timestamp columns don't store the zone, so local times written on
+	// hosts outside UTC are shifted when compared with UTC times
+	local := time.Local
+	time.Local = time.FixedZone("UTC+5", 5*60*60)
+	defer func() { time.Local = local }()
+
+	tests := []struct {
+		name     string
+		resolver staticResolver
+	}{
+		{"success", staticResolver{resolved: &ResolvedSource{Title: "Feed", StatusCode: 200}}},
+		{"not modified", staticResolver{resolved: &ResolvedSource{NotModified: true, StatusCode: 304}}},
+		{"failure", staticResolver{err: errors.New("failed")}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			m, sources, _ := newTestManager(test.resolver)
+			before := time.Now()
+			m.processSource(sourceQueueEntry{id: 1, url: "https://example.com/feed"})
+
+			if sources.scheduled.IsZero() {
+				t.Fatal("source isn't scheduled")
+			}
+			if sources.scheduled.Location() != time.UTC {
+				t.Errorf("next fetch is scheduled in %s", sources.scheduled.Location())
+			}
+			if !sources.scheduled.After(before) {
+				t.Errorf("next fetch %s is before %s", sources.scheduled, before)
+			}
+			if !sources.succeeded.IsZero() && sources.succeeded.Location() != time.UTC {
+				t.Errorf("success is recorded in %s", sources.succeeded.Location())
+			}
+		})
+	}
+}
+
+func TestProcessSourceKeepsValidatorsUntilPostsAreStored(t *testing.T) {
+	resolved := &ResolvedSource{
+		Title:        "Feed",
+		StatusCode:   200,
+		ETag:         `"new"`,
+		LastModified: "Mon, 02 Jan 2006 15:04:05 GMT",
+		Items:        []*Item{{Guid: "1", Title: "Post", Url: "https://example.com/1"}},
+	}
+	entry := sourceQueueEntry{id: 1, url: "https://example.com/feed", etag: `"old"`, lastModified: "Sun, 01 Jan 2006 15:04:05 GMT"}
+
+	t.Run("stored", func(t *testing.T) {
+		m, sources, posts := newTestManager(staticResolver{resolved: resolved})
+		m.processSource(entry)
+
+		if len(posts.upserted) != 1 {
+			t.Fatalf("%v posts are stored, want 1", len(posts.upserted))
+		}
+		if sources.updated == nil || sources.updated.ETag != resolved.ETag || sources.updated.LastModified != resolved.LastModified {
+			t.Errorf("validators aren't updated: %+v", sources.updated)
+		}
+		if want := []string{"success", "upsert", "update", "schedule"}; !reflect.DeepEqual(sources.calls, want) {
+			t.Errorf("got calls %v, want %v", sources.calls, want)
+		}
+	})
+
+	t.Run("failed", func(t *testing.T) {
+		m, sources, posts := newTestManager(staticResolver{resolved: resolved})
+		posts.err = errors.New("failed")
+		m.processSource(entry)
+
+		if sources.updated == nil {
+			t.Fatal("source isn't updated")
+		}
+		if sources.updated.ETag != entry.etag || sources.updated.LastModified != entry.lastModified {
+			t.Errorf("got validators %q and %q, want the previous ones", sources.updated.ETag, sources.updated.LastModified)
+		}
+		if sources.updated.Title != "Feed" {
+			t.Errorf("source details aren't updated")
+		}
+	})
+}
@@ -0,0 +1,137 @@
+package sources
+
+import (
+	"errors"
+	"net/url"
+	"sync"
+)
+
+var errQueueFull = errors.New("source queue is full")
+
+type sourceQueueEntry struct {
+	id           int
+	url          string
+	etag         string
+	lastModified string
+}
+
+// host returns the key used for limiting concurrent fetches
+func (e sourceQueueEntry) host() string {
+	if parsed, err := url.Parse(e.url); err == nil && parsed.Host != "" {
+		return parsed.Host
+	}
+	return e.url
+}
+
+// sourceQueue is a bounded queue of sources waiting to be fetched. Sources
+// that are already queued or being fetched are not added again.
+type sourceQueue struct {
+	mu   sync.Mutex
+	cond *sync.Cond
+
+	pending  []sourceQueueEntry
+	queued   map[int]bool
+	inFlight map[int]bool
+	hosts    map[string]int
+	closed   bool
+
+	// Maximum number of pending entries
+	capacity int
+
+	// Maximum number of concurrent fetches per host, zero means unlimited
+	hostLimit int
+}
+
+func newSourceQueue(capacity int, hostLimit int) *sourceQueue {
+	q := &sourceQueue{
+		queued:    map[int]bool{},
+		inFlight:  map[int]bool{},
+		hosts:     map[string]int{},
+		capacity:  capacity,
+		hostLimit: hostLimit,
+	}
+	q.cond = sync.NewCond(&q.mu)
+	return q
+}
+
+// Push adds entry to the queue. Sources already queued or in flight are
+// skipped, errQueueFull is returned when the queue is full.
+func (q *sourceQueue) Push(entry sourceQueueEntry) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if q.closed || q.queued[entry.id] || q.inFlight[entry.id] {
+		return nil
+	}
+	if len(q.pending) >= q.capacity {
+		return errQueueFull
+	}
+
+	q.pending = append(q.pending, entry)
+	q.queued[entry.id] = true
+	q.cond.Signal()
+	return nil
+}
+
+// Pop blocks until an entry which host is below the concurrency limit is
+// available and marks it as in flight. False is returned when the queue is
+// closed.
+func (q *sourceQueue) Pop() (sourceQueueEntry, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	for {
+		if q.closed {
+			return sourceQueueEntry{}, false
+		}
+
+		for i, entry := range q.pending {
+			host := entry.host()
+			if q.hostLimit > 0 && q.hosts[host] >= q.hostLimit {
+				continue
+			}
+
+			q.pending = append(q.pending[:i], q.pending[i+1:]...)
+			delete(q.queued, entry.id)
+			q.inFlight[entry.id] = true
+			q.hosts[host]++
+			return entry, true
+		}
+
+		q.cond.Wait()
+	}
+}
+
+// Done marks entry returned by Pop as no longer in flight
+func (q *sourceQueue) Done(entry sourceQueueEntry) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	host := entry.host()
+	delete(q.inFlight, entry.id)
+	if q.hosts[host]--; q.hosts[host] <= 0 {
+		delete(q.hosts, host)
+	}
+
+	// Entries of the host might be waiting
+	q.cond.Broadcast()
+}
+
+// Close abandons pending entries and wakes up all waiting consumers
+func (q *sourceQueue) Close() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	q.closed = true
+	q.pending = nil
+	q.queued = map[int]bool{}
+	q.cond.Broadcast()
+}
+
+// Len returns number of pending and in flight entries
+func (q *sourceQueue) Len() (pending int, inFlight int) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	return len(q.pending), len(q.inFlight)
+}
@@ -0,0 +1,87 @@
+package sources
+
+import (
+	"context"
+	"net/url"
+	"path"
+	"strings"
+)
+
+// Matcher reports whether a resolver handles the given source url
+type Matcher func(sourceUrl string, parsed *url.URL) bool
+
+// MatchScheme matches urls with one of the given schemes
+func MatchScheme(schemes ...string) Matcher {
+	return func(_ string, parsed *url.URL) bool {
+		for _, scheme := range schemes {
+			if strings.EqualFold(parsed.Scheme, scheme) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// MatchHost matches http(s) urls which host matches the given pattern, see
+// path.Match for the pattern syntax
+func MatchHost(pattern string) Matcher {
+	return func(_ string, parsed *url.URL) bool {
+		if parsed.Scheme != "http" && parsed.Scheme != "https" {
+			return false
+		}
+		matched, _ := path.Match(pattern, strings.ToLower(parsed.Hostname()))
+		return matched
+	}
+}
+
+// MatchType matches urls having explicit type prefix, like "github:owner/repo"
+func MatchType(prefix string) Matcher {
+	return func(sourceUrl string, _ *url.URL) bool {
+		return strings.HasPrefix(sourceUrl, prefix+":")
+	}
+}
+
+type registryEntry struct {
+	match    Matcher
+	resolver Resolver
+}
+
+// Registry dispatches sources to the first registered resolver matching
+// their url, the fallback resolver is used when no resolver matches
+type Registry struct {
+	entries  []registryEntry
+	fallback Resolver
+}
+
+func NewRegistry(fallback Resolver) *Registry {
+	return &Registry{fallback: fallback}
+}
+
+// Register adds resolver for urls matched by the given matcher
+func (r *Registry) Register(match Matcher, resolver Resolver) *Registry {
+	r.entries = append(r.entries, registryEntry{match, resolver})
+	return r
+}
+
+// Lookup returns resolver registered for the given url, nil is returned if
+// no resolver other than fallback is found
+func (r *Registry) Lookup(sourceUrl string) Resolver {
+	parsed, err := url.Parse(sourceUrl)
+	if err != nil {
+		return nil
+	}
+
+	for _, entry := range r.entries {
+		if entry.match(sourceUrl, parsed) {
+			return entry.resolver
+		}
+	}
+	return nil
+}
+
+func (r *Registry) Resolve(ctx context.Context, req Request) (*ResolvedSource, error) {
+	if resolver := r.Lookup(req.Url); resolver != nil {
+		return resolver.Resolve(ctx, req)
+	}
+	return r.fallback.Resolve(ctx, req)
+}
@@ -0,0 +1,88 @@
+package sources
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// namedResolver resolves every source to a source titled by its name
+type namedResolver string
+
+func (r namedResolver) Resolve(ctx context.Context, req Request) (*ResolvedSource, error) {
+	return &ResolvedSource{Title: string(r)}, nil
+}
+
+func TestRegistryDispatch(t *testing.T) {
+	registry := NewRegistry(namedResolver("fallback")).
+		Register(MatchType("github"), namedResolver("type")).
+		Register(MatchHost("*.example.com"), namedResolver("subdomain")).
+		Register(MatchHost("example.com"), namedResolver("host")).
+		Register(MatchScheme("gemini", "gopher"), namedResolver("scheme")).
+		// Never used since the earlier entries match first
+		Register(MatchHost("blog.example.com"), namedResolver("shadowed"))
+
+	tests := []struct {
+		url  string
+		want string
+	}{
+		{"github:owner/repo", "type"},
+		{"https://blog.example.com/feed", "subdomain"},
+		{"http://EXAMPLE.com/rss", "host"},
+		{"ftp://example.com/rss", "fallback"},
+		{"GEMINI://capsule.test/", "scheme"},
+		{"gopher://hole.test/", "scheme"},
+		{"https://other.test/feed", "fallback"},
+		{"https://example.com.evil.test/feed", "fallback"},
+		{"::invalid", "fallback"},
+	}
+	for _, test := range tests {
+		source, err := registry.Resolve(context.Background(), Request{Url: test.url})
+		if err != nil {
+			t.Errorf("%s: %s", test.url, err)
+			continue
+		}
+		if source.Title != test.want {
+			t.Errorf("%s resolved by %s, want %s", test.url, source.Title, test.want)
+		}
+	}
+}
+
+func TestRegistryLookup(t *testing.T) {
+	registry := NewRegistry(namedResolver("fallback")).Register(MatchHost("example.com"), namedResolver("host"))
+
+	if resolver := registry.Lookup("https://example.com"); resolver != namedResolver("host") {
+		t.Errorf("unexpected resolver %v", resolver)
+	}
+	// Fallback isn't returned by lookup
+	if resolver := registry.Lookup("https://other.test"); resolver != nil {
+		t.Errorf("unexpected resolver %v", resolver)
+	}
+}
+
+func TestRegistryResolvesWithMatchingFeedResolver(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/rss+xml")
+		w.Write([]byte(testRSS))
+	}))
+	defer server.Close()
+
+	registry := NewRegistry(failingResolver{}).
+		Register(MatchHost("127.0.0.1"), NewFeedResolver(server.Client()))
+
+	source, err := registry.Resolve(context.Background(), Request{Url: server.URL + "/feed.xml"})
+	if err != nil {
+		t.Fatalf("resolve failed: %s", err)
+	}
+	if source.Title != "Test feed" || len(source.Items) != 2 {
+		t.Errorf("unexpected source %+v", source)
+	}
+}
+
+type failingResolver struct{}
+
+func (failingResolver) Resolve(ctx context.Context, req Request) (*ResolvedSource, error) {
+	return nil, errors.New("fallback is used")
+}
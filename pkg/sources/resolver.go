@@ -1,75 +1,253 @@
 package sources
 
 import (
+	"bytes"
 	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
 	"net/url"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/mmcdole/gofeed"
+	"github.com/mmcdole/gofeed/rss"
 )
 
 type Item struct {
+	Guid        string
 	Title       string
 	Description string
 	Url         string
+	Author      string
+	Categories  []string
 	PublishedAt *time.Time
 	UpdatedAt   *time.Time
 }
 
+// Key returns stable identity of the item: its GUID, falling back to link
+// and then to hash of the item contents
+func (i *Item) Key() string {
+	if i.Guid != "" {
+		return i.Guid
+	}
+	if i.Url != "" {
+		return i.Url
+	}
+	hash := sha1.Sum([]byte(i.Title + "\n" + i.Description))
+	return hex.EncodeToString(hash[:])
+}
+
+// Request describes a single source fetch
+type Request struct {
+	Url string
+
+	// Cache validators returned by the previous fetch, used for
+	// sending conditional requests
+	ETag         string
+	LastModified string
+}
+
 type ResolvedSource struct {
-	Title string
-	Items []*Item
+	Title       string
+	Description string
+	SiteUrl     string
+	ImageUrl    string
+	Items       []*Item
+
+	// StatusCode is the HTTP status of the response, if any
+	StatusCode int
+
+	// Cache validators returned by the server
+	ETag         string
+	LastModified string
+
+	// NotModified is set when server responded with 304 Not Modified, in
+	// that case Title and Items are left empty
+	NotModified bool
+
+	// TTL is the refresh interval advertised by the feed itself
+	TTL time.Duration
+
+	// Expires is the time until the response is considered fresh
+	Expires time.Time
+
+	// Skipped are the feed items that couldn't be mapped
+	Skipped []SkippedItem
+}
+
+// SkippedItem describes a feed item ignored while resolving the source
+type SkippedItem struct {
+	Title  string
+	Link   string
+	Reason string
+}
+
+func (s *ResolvedSource) skip(item *gofeed.Item, reason string) {
+	s.Skipped = append(s.Skipped, SkippedItem{
+		Title:  item.Title,
+		Link:   item.Link,
+		Reason: reason,
+	})
+}
+
+// HTTPError is returned when the source responds with unsuccessful status
+type HTTPError struct {
+	StatusCode int
+	Status     string
+
+	// RetryAfter is the delay requested by the server
+	RetryAfter time.Duration
+}
+
+func (e *HTTPError) Error() string {
+	return fmt.Sprintf("http error: %s", e.Status)
 }
 
 type Resolver interface {
-	Resolve(url string) (*ResolvedSource, error)
+	Resolve(ctx context.Context, req Request) (*ResolvedSource, error)
 }
 
-type resolver struct{}
+// NewFeedResolver creates a generic resolver for RSS, Atom and JSON feeds.
+// Client not connecting to private network addresses is used when client is
+// nil.
+func NewFeedResolver(client *http.Client) Resolver {
+	return &resolver{client}
+}
+
+type resolver struct {
+	client *http.Client
+}
 
-func (r *resolver) Resolve(feedUrl string) (*ResolvedSource, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+func (r *resolver) httpClient() *http.Client {
+	if r.client != nil {
+		return r.client
+	}
+	return publicClient
+}
+
+func (r *resolver) Resolve(ctx context.Context, req Request) (*ResolvedSource, error) {
+	ctx, cancel := context.WithTimeout(ctx, 60*time.Second)
 	defer cancel()
 
 	// Parse url
-	parsedUrl, err := url.Parse(feedUrl)
+	parsedUrl, err := url.Parse(req.Url)
+	if err != nil {
+		return nil, err
+	}
+
+	// Create conditional request
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodGet, req.Url, nil)
+	if err != nil {
+		return nil, err
+	}
+	httpReq.Header.Set("User-Agent", "myfeed/1.0")
+	if req.ETag != "" {
+		httpReq.Header.Set("If-None-Match", req.ETag)
+	}
+	if req.LastModified != "" {
+		httpReq.Header.Set("If-Modified-Since", req.LastModified)
+	}
+
+	resp, err := r.httpClient().Do(httpReq)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	now := time.Now()
+
+	if resp.StatusCode == http.StatusNotModified {
+		return &ResolvedSource{
+			ETag:         firstNonEmpty(resp.Header.Get("ETag"), req.ETag),
+			LastModified: firstNonEmpty(resp.Header.Get("Last-Modified"), req.LastModified),
+			NotModified:  true,
+			StatusCode:   resp.StatusCode,
+			Expires:      parseExpires(resp.Header, now),
+		}, nil
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, &HTTPError{
+			StatusCode: resp.StatusCode,
+			Status:     resp.Status,
+			RetryAfter: parseRetryAfter(resp.Header, now),
+		}
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxFeedSize))
 	if err != nil {
 		return nil, err
 	}
 
 	// Parse feed
 	parser := gofeed.NewParser()
-	feed, err := parser.ParseURLWithContext(feedUrl, ctx)
+	parser.RSSTranslator = &rssTranslator{}
+	feed, err := parser.Parse(bytes.NewReader(body))
 	if err != nil {
 		return nil, err
 	}
 
+	// Relative links are resolved against xml:base, feed link or the feed
+	// url in that order
+	siteUrl := resolveLink(parsedUrl, feed.Link)
+	base := parsedUrl
+	if xmlBase := documentBase(body, parsedUrl); xmlBase != nil {
+		base = xmlBase
+	} else if siteUrl != nil {
+		base = siteUrl
+	}
+
 	// Map feed
 	source := &ResolvedSource{
-		Title: parsedUrl.Hostname(),
-		Items: make([]*Item, len(feed.Items)),
+		Title:        firstNonEmpty(strings.TrimSpace(feed.Title), parsedUrl.Hostname()),
+		Description:  strings.TrimSpace(feed.Description),
+		Items:        make([]*Item, len(feed.Items)),
+		ETag:         resp.Header.Get("ETag"),
+		LastModified: resp.Header.Get("Last-Modified"),
+		StatusCode:   resp.StatusCode,
+		TTL:          feedTTL(feed),
+		Expires:      parseExpires(resp.Header, now),
+	}
+
+	if siteUrl != nil {
+		source.SiteUrl = siteUrl.String()
+	}
+	if feed.Image != nil {
+		if imageUrl := resolveLink(base, feed.Image.URL); imageUrl != nil {
+			source.ImageUrl = imageUrl.String()
+		}
 	}
 
 	// Map items
 	i := 0
 	for _, item := range feed.Items {
-		itemUrl := item.Link
-
-		if itemUrl == "" {
-			// Skip empty posts
+		if strings.TrimSpace(item.Link) == "" {
+			source.skip(item, "missing link")
 			continue
 		}
 
-		if !strings.HasPrefix(itemUrl, "https://") && !strings.HasPrefix(itemUrl, "http://") {
-			// Support relative URLs
+		itemUrl, err := base.Parse(strings.TrimSpace(item.Link))
+		if err != nil {
+			source.skip(item, fmt.Sprintf("invalid link: %s", err))
+			continue
+		}
+		if itemUrl.Scheme != "http" && itemUrl.Scheme != "https" {
+			source.skip(item, fmt.Sprintf("unsupported link scheme '%s'", itemUrl.Scheme))
 			continue
 		}
 
 		source.Items[i] = &Item{
-			Url:         itemUrl,
+			Guid:        item.GUID,
+			Url:         itemUrl.String(),
 			Title:       item.Title,
 			Description: item.Description,
+			Author:      itemAuthor(item),
+			Categories:  item.Categories,
 			PublishedAt: item.PublishedParsed,
 			UpdatedAt:   item.UpdatedParsed,
 		}
@@ -80,3 +258,178 @@ func (r *resolver) Resolve(feedUrl string) (*ResolvedSource, error) {
 
 	return source, nil
 }
+
+// Maximum number of bytes read from feed documents
+const maxFeedSize = 20 << 20
+
+// resolveLink resolves possibly relative or protocol-relative link against
+// the base url, nil is returned for empty or invalid links
+func resolveLink(base *url.URL, link string) *url.URL {
+	link = strings.TrimSpace(link)
+	if link == "" {
+		return nil
+	}
+	resolved, err := base.Parse(link)
+	if err != nil {
+		return nil
+	}
+	return resolved
+}
+
+// documentBase returns xml:base of the feed document declared on the root
+// or channel elements
+func documentBase(body []byte, feedUrl *url.URL) *url.URL {
+	var base *url.URL
+	decoder := xml.NewDecoder(bytes.NewReader(body))
+	decoder.Strict = false
+
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			return base
+		}
+
+		element, ok := token.(xml.StartElement)
+		if !ok {
+			continue
+		}
+
+		for _, attr := range element.Attr {
+			if attr.Name.Space == "http://www.w3.org/XML/1998/namespace" && attr.Name.Local == "base" {
+				parent := feedUrl
+				if base != nil {
+					parent = base
+				}
+				if resolved := resolveLink(parent, attr.Value); resolved != nil {
+					base = resolved
+				}
+			}
+		}
+
+		// Only root and channel elements are considered
+		if element.Name.Local != "rss" && element.Name.Local != "RDF" && element.Name.Local != "feed" {
+			return base
+		}
+	}
+}
+
+// itemAuthor returns names of the item authors
+func itemAuthor(item *gofeed.Item) string {
+	var names []string
+	for _, author := range item.Authors {
+		if author != nil && strings.TrimSpace(author.Name) != "" {
+			names = append(names, strings.TrimSpace(author.Name))
+		}
+	}
+	if len(names) == 0 && item.Author != nil {
+		return strings.TrimSpace(item.Author.Name)
+	}
+	return strings.Join(names, ", ")
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, value := range values {
+		if value != "" {
+			return value
+		}
+	}
+	return ""
+}
+
+// rssTranslator keeps RSS specific fields that are dropped by the default
+// translator in the custom fields of the translated feed
+type rssTranslator struct {
+	gofeed.DefaultRSSTranslator
+}
+
+func (t *rssTranslator) Translate(feed interface{}) (*gofeed.Feed, error) {
+	result, err := t.DefaultRSSTranslator.Translate(feed)
+	if err != nil {
+		return nil, err
+	}
+
+	if rssFeed, ok := feed.(*rss.Feed); ok && rssFeed.TTL != "" {
+		if result.Custom == nil {
+			result.Custom = map[string]string{}
+		}
+		result.Custom["ttl"] = rssFeed.TTL
+	}
+
+	return result, nil
+}
+
+// feedTTL returns refresh interval advertised using RSS <ttl> element or
+// syndication module
+func feedTTL(feed *gofeed.Feed) time.Duration {
+	if minutes, err := strconv.Atoi(feed.Custom["ttl"]); err == nil && minutes > 0 {
+		return time.Duration(minutes) * time.Minute
+	}
+
+	sy, ok := feed.Extensions["sy"]
+	if !ok {
+		return 0
+	}
+
+	var period time.Duration
+	if values := sy["updatePeriod"]; len(values) > 0 {
+		switch strings.TrimSpace(values[0].Value) {
+		case "hourly":
+			period = time.Hour
+		case "daily":
+			period = 24 * time.Hour
+		case "weekly":
+			period = 7 * 24 * time.Hour
+		case "monthly":
+			period = 30 * 24 * time.Hour
+		case "yearly":
+			period = 365 * 24 * time.Hour
+		}
+	}
+
+	frequency := 1
+	if values := sy["updateFrequency"]; len(values) > 0 {
+		if value, err := strconv.Atoi(strings.TrimSpace(values[0].Value)); err == nil && value > 0 {
+			frequency = value
+		}
+	}
+
+	return period / time.Duration(frequency)
+}
+
+// parseExpires returns freshness lifetime of the response using
+// Cache-Control max-age directive or Expires header
+func parseExpires(header http.Header, now time.Time) time.Time {
+	for _, directive := range strings.Split(header.Get("Cache-Control"), ",") {
+		directive = strings.TrimSpace(strings.ToLower(directive))
+		switch {
+		case directive == "no-cache" || directive == "no-store":
+			return time.Time{}
+		case strings.HasPrefix(directive, "max-age="):
+			if seconds, err := strconv.Atoi(directive[len("max-age="):]); err == nil {
+				return now.Add(time.Duration(seconds) * time.Second)
+			}
+		}
+	}
+
+	if expires, err := http.ParseTime(header.Get("Expires")); err == nil {
+		return expires
+	}
+
+	return time.Time{}
+}
+
+// parseRetryAfter returns delay requested using Retry-After header which
+// might contain either delay in seconds or a date
+func parseRetryAfter(header http.Header, now time.Time) time.Duration {
+	value := header.Get("Retry-After")
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		return time.Duration(seconds) * time.Second
+	}
+	if date, err := http.ParseTime(value); err == nil {
+		return date.Sub(now)
+	}
+	return 0
+}
@@ -0,0 +1,120 @@
+package sources
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+const testRSS = `<?xml version="1.0"?>
+<rss version="2.0">
+  <channel>
+    <title>Test feed</title>
+    <link>https://site.test/blog/</link>
+    <ttl>30</ttl>
+    <item>
+      <title>First</title>
+      <link>posts/first</link>
+      <guid isPermaLink="false">first-guid</guid>
+      <description>&lt;p&gt;Hello&lt;/p&gt;</description>
+    </item>
+    <item>
+      <title>Second</title>
+      <link>https://site.test/second</link>
+    </item>
+    <item>
+      <title>Script</title>
+      <link>javascript:alert(1)</link>
+    </item>
+  </channel>
+</rss>`
+
+func TestFeedResolver(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(testRSS))
+	}))
+	defer server.Close()
+
+	source, err := NewFeedResolver(server.Client()).Resolve(context.Background(), Request{Url: server.URL})
+	if err != nil {
+		t.Fatalf("resolve failed: %s", err)
+	}
+
+	if source.Title != "Test feed" || source.SiteUrl != "https://site.test/blog/" || source.TTL != 30*time.Minute {
+		t.Errorf("unexpected source %+v", source)
+	}
+	if len(source.Items) != 2 || len(source.Skipped) != 1 {
+		t.Fatalf("got %v items and %v skipped, want 2 and 1", len(source.Items), len(source.Skipped))
+	}
+
+	// Relative links are resolved against the site url
+	first := source.Items[0]
+	if first.Url != "https://site.test/blog/posts/first" || first.Key() != "first-guid" || first.Description != "<p>Hello</p>" {
+		t.Errorf("unexpected item %+v", first)
+	}
+	if second := source.Items[1]; second.Key() != "https://site.test/second" {
+		t.Errorf("unexpected item key %s", second.Key())
+	}
+}
+
+func TestFeedResolverConditionalRequest(t *testing.T) {
+	const etag = `"v1"`
+	const lastModified = "Mon, 02 Jan 2006 15:04:05 GMT"
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if r.Header.Get("If-None-Match") == etag && r.Header.Get("If-Modified-Since") == lastModified {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", etag)
+		w.Header().Set("Last-Modified", lastModified)
+		w.Write([]byte(testRSS))
+	}))
+	defer server.Close()
+
+	resolver := NewFeedResolver(server.Client())
+	source, err := resolver.Resolve(context.Background(), Request{Url: server.URL})
+	if err != nil {
+		t.Fatalf("resolve failed: %s", err)
+	}
+	if source.NotModified || source.ETag != etag || source.LastModified != lastModified {
+		t.Fatalf("unexpected source %+v", source)
+	}
+
+	// Validators of the previous response are sent back
+	source, err = resolver.Resolve(context.Background(), Request{Url: server.URL, ETag: source.ETag, LastModified: source.LastModified})
+	if err != nil {
+		t.Fatalf("conditional resolve failed: %s", err)
+	}
+	if !source.NotModified || source.StatusCode != http.StatusNotModified || len(source.Items) != 0 {
+		t.Errorf("unexpected source %+v", source)
+	}
+	// Validators are kept when 304 response doesn't repeat them
+	if source.ETag != etag || source.LastModified != lastModified {
+		t.Errorf("validators are lost: %q %q", source.ETag, source.LastModified)
+	}
+	if requests != 2 {
+		t.Errorf("server got %v requests, want 2", requests)
+	}
+}
+
+func TestFeedResolverHTTPError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Retry-After", "120")
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	_, err := NewFeedResolver(server.Client()).Resolve(context.Background(), Request{Url: server.URL})
+	var httpErr *HTTPError
+	if !errors.As(err, &httpErr) {
+		t.Fatalf("got %v, want HTTPError", err)
+	}
+	if httpErr.StatusCode != http.StatusServiceUnavailable || httpErr.RetryAfter != 2*time.Minute {
+		t.Errorf("unexpected error %+v", httpErr)
+	}
+}
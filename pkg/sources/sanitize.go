@@ -0,0 +1,247 @@
+package sources
+
+import (
+	"bytes"
+	"net/url"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// Elements allowed in sanitized html with their allowed attributes
+var allowedElements = map[string]map[string]bool{
+	"a":          {"href": true, "title": true},
+	"abbr":       {"title": true},
+	"b":          {},
+	"blockquote": {"cite": true},
+	"br":         {},
+	"caption":    {},
+	"cite":       {},
+	"code":       {},
+	"dd":         {},
+	"del":        {},
+	"dfn":        {},
+	"div":        {},
+	"dl":         {},
+	"dt":         {},
+	"em":         {},
+	"figcaption": {},
+	"figure":     {},
+	"h1":         {},
+	"h2":         {},
+	"h3":         {},
+	"h4":         {},
+	"h5":         {},
+	"h6":         {},
+	"hr":         {},
+	"i":          {},
+	"img":        {"src": true, "alt": true, "title": true, "width": true, "height": true},
+	"ins":        {},
+	"kbd":        {},
+	"li":         {},
+	"mark":       {},
+	"ol":         {"start": true},
+	"p":          {},
+	"pre":        {},
+	"q":          {"cite": true},
+	"s":          {},
+	"samp":       {},
+	"small":      {},
+	"span":       {},
+	"strike":     {},
+	"strong":     {},
+	"sub":        {},
+	"sup":        {},
+	"table":      {},
+	"tbody":      {},
+	"td":         {"colspan": true, "rowspan": true},
+	"tfoot":      {},
+	"th":         {"colspan": true, "rowspan": true},
+	"thead":      {},
+	"time":       {"datetime": true},
+	"tr":         {},
+	"u":          {},
+	"ul":         {},
+}
+
+// Elements which are dropped together with their contents
+var droppedElements = map[string]bool{
+	"script":   true,
+	"style":    true,
+	"iframe":   true,
+	"object":   true,
+	"embed":    true,
+	"noscript": true,
+	"template": true,
+	"svg":      true,
+	"math":     true,
+	"head":     true,
+	"title":    true,
+	"textarea": true,
+	"select":   true,
+}
+
+// Elements without closing tags
+var voidElements = map[string]bool{
+	"br":  true,
+	"hr":  true,
+	"img": true,
+}
+
+// Attributes containing urls
+var urlAttributes = map[string]bool{
+	"href": true,
+	"src":  true,
+	"cite": true,
+}
+
+// SanitizeHTML returns html keeping only allowlisted elements and attributes.
+// Relative urls are resolved against base, urls with schemes other than
+// http(s) and mailto are removed.
+func SanitizeHTML(s string, base *url.URL) string {
+	var out bytes.Buffer
+	var open []string
+	skip := 0
+
+	tokenizer := html.NewTokenizer(strings.NewReader(s))
+	for {
+		tokenType := tokenizer.Next()
+		if tokenType == html.ErrorToken {
+			break
+		}
+
+		token := tokenizer.Token()
+		switch tokenType {
+		case html.TextToken:
+			if skip == 0 {
+				out.WriteString(html.EscapeString(token.Data))
+			}
+
+		case html.StartTagToken, html.SelfClosingTagToken:
+			if droppedElements[token.Data] {
+				if tokenType == html.StartTagToken && !voidElements[token.Data] {
+					skip++
+				}
+				continue
+			}
+
+			attrs, ok := allowedElements[token.Data]
+			if skip > 0 || !ok {
+				continue
+			}
+
+			out.WriteByte('<')
+			out.WriteString(token.Data)
+			for _, attr := range token.Attr {
+				if attr.Namespace != "" || !attrs[attr.Key] {
+					continue
+				}
+				value := attr.Val
+				if urlAttributes[attr.Key] {
+					if value = sanitizeUrl(value, base, attr.Key == "href"); value == "" {
+						continue
+					}
+				}
+				out.WriteByte(' ')
+				out.WriteString(attr.Key)
+				out.WriteString(`="`)
+				out.WriteString(html.EscapeString(value))
+				out.WriteByte('"')
+			}
+			if token.Data == "a" {
+				out.WriteString(` rel="nofollow noopener noreferrer"`)
+			}
+			out.WriteByte('>')
+
+			if !voidElements[token.Data] && tokenType == html.StartTagToken {
+				open = append(open, token.Data)
+			}
+
+		case html.EndTagToken:
+			if droppedElements[token.Data] {
+				if skip > 0 {
+					skip--
+				}
+				continue
+			}
+			if skip > 0 {
+				continue
+			}
+
+			// Close elements up to the matching open element
+			for i := len(open) - 1; i >= 0; i-- {
+				if open[i] != token.Data {
+					continue
+				}
+				for j := len(open) - 1; j >= i; j-- {
+					out.WriteString("</" + open[j] + ">")
+				}
+				open = open[:i]
+				break
+			}
+		}
+	}
+
+	// Close elements left open
+	for i := len(open) - 1; i >= 0; i-- {
+		out.WriteString("</" + open[i] + ">")
+	}
+
+	return strings.TrimSpace(out.String())
+}
+
+// PlainText returns text content of the given html with collapsed whitespace.
+// Entities are decoded, so "&lt;script&gt;" becomes a literal "<script>" and
+// the result must be escaped wherever it's written as html.
+func PlainText(s string) string {
+	var out strings.Builder
+	skip := 0
+
+	tokenizer := html.NewTokenizer(strings.NewReader(s))
+	for {
+		tokenType := tokenizer.Next()
+		if tokenType == html.ErrorToken {
+			break
+		}
+
+		token := tokenizer.Token()
+		switch tokenType {
+		case html.TextToken:
+			if skip == 0 {
+				out.WriteString(token.Data)
+			}
+		case html.StartTagToken:
+			if droppedElements[token.Data] {
+				skip++
+			}
+		case html.EndTagToken:
+			if droppedElements[token.Data] && skip > 0 {
+				skip--
+			}
+		}
+	}
+
+	return strings.Join(strings.Fields(out.String()), " ")
+}
+
+// sanitizeUrl resolves url against base and returns it if it uses one of the
+// allowed schemes, otherwise empty string is returned
+func sanitizeUrl(value string, base *url.URL, allowMailto bool) string {
+	parsed, err := url.Parse(strings.TrimSpace(value))
+	if err != nil {
+		return ""
+	}
+	if base != nil {
+		parsed = base.ResolveReference(parsed)
+	}
+
+	switch parsed.Scheme {
+	case "http", "https":
+		return parsed.String()
+	case "mailto":
+		if allowMailto {
+			return parsed.String()
+		}
+	}
+	return ""
+}
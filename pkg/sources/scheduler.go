@@ -0,0 +1,99 @@
+package sources
+
+import (
+	"errors"
+	"sort"
+	"time"
+)
+
+// Scheduler computes when the source should be fetched next
+type Scheduler struct {
+	MinInterval time.Duration
+	MaxInterval time.Duration
+
+	// Sources are disabled after FailureThreshold consecutive failures,
+	// disabled sources are retried with exponential backoff up to MaxBackoff
+	FailureThreshold int
+	MaxBackoff       time.Duration
+}
+
+// Disabled reports whether source with given number of consecutive failures
+// is disabled
+func (s *Scheduler) Disabled(failures int) bool {
+	return s.FailureThreshold > 0 && failures >= s.FailureThreshold
+}
+
+// Next returns the time of the next fetch after a successful fetch. Posting
+// frequency is estimated from given publish times of the source posts.
+func (s *Scheduler) Next(now time.Time, resolved *ResolvedSource, published []time.Time) time.Time {
+	interval := s.postingInterval(now, published)
+
+	// Respect publisher hints
+	if resolved.TTL > interval {
+		interval = resolved.TTL
+	}
+	if !resolved.Expires.IsZero() && resolved.Expires.Sub(now) > interval {
+		interval = resolved.Expires.Sub(now)
+	}
+
+	return now.Add(s.clamp(interval))
+}
+
+// NextAfterError returns the time of the next fetch after a failed fetch,
+// failures is the number of consecutive failures including this one
+func (s *Scheduler) NextAfterError(now time.Time, err error, failures int) time.Time {
+	if s.Disabled(failures) {
+		backoff := s.MaxInterval
+		for i := s.FailureThreshold; i < failures && backoff < s.MaxBackoff; i++ {
+			backoff *= 2
+		}
+		if backoff > s.MaxBackoff {
+			backoff = s.MaxBackoff
+		}
+		return now.Add(backoff)
+	}
+
+	var httpErr *HTTPError
+	if errors.As(err, &httpErr) && httpErr.RetryAfter > 0 {
+		return now.Add(s.clamp(httpErr.RetryAfter))
+	}
+	return now.Add(s.MinInterval)
+}
+
+// Number of recent posts used for estimating posting interval
+const recentPosts = 10
+
+// postingInterval returns half of the average interval between the recent
+// posts, or time passed since the latest post if it is longer
+func (s *Scheduler) postingInterval(now time.Time, published []time.Time) time.Duration {
+	if len(published) < 2 {
+		return s.MaxInterval
+	}
+
+	times := make([]time.Time, len(published))
+	copy(times, published)
+	sort.Slice(times, func(i, j int) bool { return times[i].After(times[j]) })
+
+	// Only consider recent posts
+	if len(times) > recentPosts {
+		times = times[:recentPosts]
+	}
+
+	newest, oldest := times[0], times[len(times)-1]
+	interval := newest.Sub(oldest) / time.Duration(len(times)-1)
+	if since := now.Sub(newest); since > interval {
+		interval = since
+	}
+
+	return interval / 2
+}
+
+func (s *Scheduler) clamp(interval time.Duration) time.Duration {
+	if interval < s.MinInterval {
+		return s.MinInterval
+	}
+	if interval > s.MaxInterval {
+		return s.MaxInterval
+	}
+	return interval
+}
@@ -41,6 +41,26 @@ func (c *Connection) Posts() (models.PostRepository, error) {
 	return newPostRepository(c)
 }
 
+func (c *Connection) SavedItems() (models.SavedItemRepository, error) {
+	return newSavedItemRepository(c)
+}
+
+func (c *Connection) Sessions() (models.SessionRepository, error) {
+	return newSessionRepository(c)
+}
+
+func (c *Connection) Identities() (models.IdentityRepository, error) {
+	return newIdentityRepository(c)
+}
+
+func (c *Connection) Tokens() (models.TokenRepository, error) {
+	return newTokenRepository(c)
+}
+
+func (c *Connection) UsedTokens() (models.UsedTokenRepository, error) {
+	return newUsedTokenRepository(c)
+}
+
 func (c *Connection) Close() error {
 	return c.db.Close()
 }
@@ -15,15 +15,18 @@ type feedRepository struct {
 	getFeedStmt      *sql.Stmt
 	removeFeedStmt   *sql.Stmt
 	updateFeedStmt   *sql.Stmt
+	getFeedRulesStmt *sql.Stmt
 }
 
 const (
 	addFeedQuery           = `INSERT INTO feeds (name, user_id, is_public) VALUES ($1, $2, $3) RETURNING id`
-	getUserFeedsQuery      = `SELECT id, name, user_id, is_public FROM feeds WHERE user_id = $1`
-	getFeedQuery           = `SELECT id, name, user_id, is_public FROM feeds WHERE id = $1`
+	getUserFeedsQuery      = `SELECT id, name, user_id, is_public, retention_days, retention_posts FROM feeds WHERE user_id = $1`
+	getFeedQuery           = `SELECT id, name, user_id, is_public, retention_days, retention_posts FROM feeds WHERE id = $1`
 	removeFeedQuery        = `DELETE FROM feeds WHERE id = $1`
-	updateFeedQuery        = `UPDATE feeds SET name = $1, is_public = $2 WHERE id = $3`
+	updateFeedQuery        = `UPDATE feeds SET name = $1, is_public = $2, retention_days = $3, retention_posts = $4 WHERE id = $5`
 	removeFeedSourcesQuery = `DELETE FROM feed_source WHERE feed_id = $1`
+	getFeedRulesQuery      = `SELECT id, action, field, matcher, pattern FROM feed_rules WHERE feed_id = $1 ORDER BY position`
+	removeFeedRulesQuery   = `DELETE FROM feed_rules WHERE feed_id = $1`
 )
 
 func newFeedRepository(c *Connection) (r *feedRepository, err error) {
@@ -34,6 +37,7 @@ func newFeedRepository(c *Connection) (r *feedRepository, err error) {
 		Prepare(getFeedQuery, &r.getFeedStmt).
 		Prepare(removeFeedQuery, &r.removeFeedStmt).
 		Prepare(updateFeedQuery, &r.updateFeedStmt).
+		Prepare(getFeedRulesQuery, &r.getFeedRulesStmt).
 		Exec()
 	return
 }
@@ -61,7 +65,7 @@ func (r *feedRepository) GetUserFeeds(userId string) ([]listing.Feed, error) {
 	var result []listing.Feed
 	for rows.Next() {
 		var f feed
-		err := rows.Scan(&f.id, &f.name, &f.userId, &f.isPublic)
+		err := rows.Scan(&f.id, &f.name, &f.userId, &f.isPublic, &f.retentionDays, &f.retentionPosts)
 		if err != nil {
 			return nil, err
 		}
@@ -72,7 +76,7 @@ func (r *feedRepository) GetUserFeeds(userId string) ([]listing.Feed, error) {
 
 func (r *feedRepository) GetFeed(feedId int) (listing.Feed, error) {
 	var f feed
-	err := r.getFeedStmt.QueryRow(feedId).Scan(&f.id, &f.name, &f.userId, &f.isPublic)
+	err := r.getFeedStmt.QueryRow(feedId).Scan(&f.id, &f.name, &f.userId, &f.isPublic, &f.retentionDays, &f.retentionPosts)
 	return &f, err
 }
 
@@ -82,21 +86,23 @@ func (r *feedRepository) RemoveFeed(feedId int) error {
 }
 
 func (r *feedRepository) UpdateFeed(feedId int, data updating.Feed) error {
-	_, err := r.updateFeedStmt.Exec(data.Name, data.IsPublic, feedId)
+	_, err := r.updateFeedStmt.Exec(data.Name, data.IsPublic, data.RetentionDays, data.RetentionPosts, feedId)
 	return err
 }
 
-func (r *feedRepository) UpdateFeedSources(feedId int, sourceIds ...int) error {
+func (r *feedRepository) UpdateFeedSources(feedId int, sources ...updating.FeedSource) error {
 	// build insert query
 	var query string
-	for i, sourceId := range sourceIds {
+	params := make([]interface{}, 0, 3*len(sources))
+	for i, source := range sources {
 		var prefix string
 		if i > 0 {
 			prefix = " ,"
 		}
-		query += fmt.Sprintf("%s(%v, %v)", prefix, feedId, sourceId)
+		query += fmt.Sprintf("%s($%v, $%v, $%v)", prefix, i*3+1, i*3+2, i*3+3)
+		params = append(params, feedId, source.SourceId, source.Title)
 	}
-	query = fmt.Sprintf("INSERT INTO feed_source (feed_id, source_id) VALUES %s", query)
+	query = fmt.Sprintf("INSERT INTO feed_source (feed_id, source_id, title) VALUES %s", query)
 
 	// Apply updates
 	tx, err := r.c.db.Begin()
@@ -104,19 +110,102 @@ func (r *feedRepository) UpdateFeedSources(feedId int, sourceIds ...int) error {
 		return err
 	}
 	if _, err := tx.Exec(removeFeedSourcesQuery, feedId); err != nil {
+		_ = tx.Rollback()
 		return err
 	}
-	if _, err := tx.Exec(query); err != nil {
+	if len(sources) > 0 {
+		if _, err := tx.Exec(query, params...); err != nil {
+			_ = tx.Rollback()
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+func (r *feedRepository) GetFeedRules(feedId int) ([]listing.FeedRule, error) {
+	rows, err := r.getFeedRulesStmt.Query(feedId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var result []listing.FeedRule
+	for rows.Next() {
+		var rule feedRule
+		if err := rows.Scan(&rule.id, &rule.action, &rule.field, &rule.matcher, &rule.pattern); err != nil {
+			return nil, err
+		}
+		result = append(result, &rule)
+	}
+	return result, rows.Err()
+}
+
+func (r *feedRepository) UpdateFeedRules(feedId int, rules ...updating.FeedRule) error {
+	// build insert query
+	var query string
+	params := make([]interface{}, 0, 6*len(rules))
+	for i, rule := range rules {
+		var prefix string
+		if i > 0 {
+			prefix = " ,"
+		}
+		query += fmt.Sprintf("%s($%v, $%v, $%v, $%v, $%v, $%v)", prefix, i*6+1, i*6+2, i*6+3, i*6+4, i*6+5, i*6+6)
+		params = append(params, feedId, i, rule.Action, rule.Field, rule.Matcher, rule.Pattern)
+	}
+	query = fmt.Sprintf("INSERT INTO feed_rules (feed_id, position, action, field, matcher, pattern) VALUES %s", query)
+
+	// Apply updates
+	tx, err := r.c.db.Begin()
+	if err != nil {
 		return err
 	}
+	if _, err := tx.Exec(removeFeedRulesQuery, feedId); err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+	if len(rules) > 0 {
+		if _, err := tx.Exec(query, params...); err != nil {
+			_ = tx.Rollback()
+			return err
+		}
+	}
 	return tx.Commit()
 }
 
+type feedRule struct {
+	id      int
+	action  string
+	field   string
+	matcher string
+	pattern string
+}
+
+func (r *feedRule) Id() int {
+	return r.id
+}
+
+func (r *feedRule) Action() string {
+	return r.action
+}
+
+func (r *feedRule) Field() string {
+	return r.field
+}
+
+func (r *feedRule) Matcher() string {
+	return r.matcher
+}
+
+func (r *feedRule) Pattern() string {
+	return r.pattern
+}
+
 type feed struct {
-	id       int
-	name     string
-	userId   string
-	isPublic bool
+	id             int
+	name           string
+	userId         string
+	isPublic       bool
+	retentionDays  *int
+	retentionPosts *int
 }
 
 func (f *feed) Id() int {
@@ -134,3 +223,11 @@ func (f *feed) UserId() string {
 func (f *feed) IsPublic() bool {
 	return f.isPublic
 }
+
+func (f *feed) RetentionDays() *int {
+	return f.retentionDays
+}
+
+func (f *feed) RetentionPosts() *int {
+	return f.retentionPosts
+}
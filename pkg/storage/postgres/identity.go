@@ -0,0 +1,104 @@
+package postgres
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/themisir/myfeed/pkg/adding"
+	"github.com/themisir/myfeed/pkg/listing"
+)
+
+type identityRepository struct {
+	c                     *Connection
+	addIdentityStmt       *sql.Stmt
+	findIdentityStmt      *sql.Stmt
+	getUserIdentitiesStmt *sql.Stmt
+	removeIdentityStmt    *sql.Stmt
+}
+
+const identityColumns = `provider, subject, user_id, email, created_at`
+
+const (
+	addIdentityQuery       = `INSERT INTO user_identities (provider, subject, user_id, email) VALUES ($1, $2, $3, $4)`
+	findIdentityQuery      = `SELECT ` + identityColumns + ` FROM user_identities WHERE provider = $1 AND subject = $2`
+	getUserIdentitiesQuery = `SELECT ` + identityColumns + ` FROM user_identities WHERE user_id = $1 ORDER BY provider, created_at`
+	removeIdentityQuery    = `DELETE FROM user_identities WHERE user_id = $1 AND provider = $2 AND subject = $3`
+)
+
+func newIdentityRepository(c *Connection) (r *identityRepository, err error) {
+	r = &identityRepository{c: c}
+	err = c.Batch().
+		Prepare(addIdentityQuery, &r.addIdentityStmt).
+		Prepare(findIdentityQuery, &r.findIdentityStmt).
+		Prepare(getUserIdentitiesQuery, &r.getUserIdentitiesStmt).
+		Prepare(removeIdentityQuery, &r.removeIdentityStmt).
+		Exec()
+	return
+}
+
+func (r *identityRepository) AddIdentity(data adding.IdentityData) error {
+	_, err := r.addIdentityStmt.Exec(data.Provider, data.Subject, data.UserId, data.Email)
+	return err
+}
+
+func (r *identityRepository) FindIdentity(provider string, subject string) (listing.Identity, error) {
+	var i identity
+	err := r.findIdentityStmt.QueryRow(provider, subject).Scan(&i.provider, &i.subject, &i.userId, &i.email, &i.createdAt)
+	if err == sql.ErrNoRows {
+		return nil, listing.ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &i, nil
+}
+
+func (r *identityRepository) GetUserIdentities(userId string) ([]listing.Identity, error) {
+	rows, err := r.getUserIdentitiesStmt.Query(userId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var result []listing.Identity
+	for rows.Next() {
+		var i identity
+		if err := rows.Scan(&i.provider, &i.subject, &i.userId, &i.email, &i.createdAt); err != nil {
+			return nil, err
+		}
+		result = append(result, &i)
+	}
+	return result, rows.Err()
+}
+
+func (r *identityRepository) RemoveUserIdentity(userId string, provider string, subject string) error {
+	_, err := r.removeIdentityStmt.Exec(userId, provider, subject)
+	return err
+}
+
+type identity struct {
+	provider  string
+	subject   string
+	userId    string
+	email     string
+	createdAt time.Time
+}
+
+func (i *identity) Provider() string {
+	return i.provider
+}
+
+func (i *identity) Subject() string {
+	return i.subject
+}
+
+func (i *identity) UserId() string {
+	return i.userId
+}
+
+func (i *identity) Email() string {
+	return i.email
+}
+
+func (i *identity) CreatedAt() time.Time {
+	return i.createdAt
+}
@@ -3,9 +3,12 @@ package postgres
 import (
 	"database/sql"
 	"fmt"
+	"github.com/lib/pq"
 	"github.com/themisir/myfeed/pkg/adding"
 	"github.com/themisir/myfeed/pkg/listing"
+	"github.com/themisir/myfeed/pkg/removing"
 	"github.com/themisir/myfeed/pkg/updating"
+	"html"
 	"time"
 )
 
@@ -13,19 +16,94 @@ type postRepository struct {
 	c                        *Connection
 	addPostStmt              *sql.Stmt
 	getSourcePostsStmt       *sql.Stmt
+	getSourcePostsBeforeStmt *sql.Stmt
+	getSourcePostsAfterStmt  *sql.Stmt
 	getFeedPostsStmt         *sql.Stmt
+	getFeedPostsBeforeStmt   *sql.Stmt
+	getFeedPostsAfterStmt    *sql.Stmt
 	removeSourcePostStmt     *sql.Stmt
 	removeAllSourcePostsStmt *sql.Stmt
 	updateSourcePostStmt     *sql.Stmt
+	removeExpiredPostsStmt   *sql.Stmt
+	searchFeedPostsStmt      *sql.Stmt
+	searchUserPostsStmt      *sql.Stmt
+	searchPublicPostsStmt    *sql.Stmt
+	getUnreadCountsStmt      *sql.Stmt
+	markPostReadStmt         *sql.Stmt
+	markFeedReadStmt         *sql.Stmt
 }
 
+// Posts are listed using keyset pagination on the post key
 const (
-	addPostQuery              = `INSERT INTO posts (source_id, title, description, url, published_at, updated_at) VALUES ($1, $2, $3, $4, $5, $6) RETURNING id`
-	getSourcePostsQuery       = `SELECT id, title, description, url, published_at, updated_at FROM posts WHERE source_id = $1 ORDER BY published_at DESC, created_at DESC`
-	getFeedPostsQuery         = `SELECT p.id, p.title, p.description, p.url, p.published_at, p.updated_at, s.id, s.title, s.url FROM posts p JOIN sources s ON s.id = p.source_id JOIN feed_source fs ON fs.source_id = p.source_id WHERE fs.feed_id = $1 ORDER BY p.published_at DESC, p.created_at DESC`
+	postKey       = `COALESCE(p.published_at, p.created_at), p.id`
+	postOrderDesc = `COALESCE(p.published_at, p.created_at) DESC, p.id DESC`
+	postOrderAsc  = `COALESCE(p.published_at, p.created_at), p.id`
+
+	sourcePostsSelect = `SELECT p.id, p.title, p.description, p.url, p.author, p.categories, p.published_at, p.updated_at, COALESCE(p.published_at, p.created_at) FROM posts p WHERE p.source_id = $1`
+	feedPostsSelect   = `SELECT p.id, p.title, p.description, p.url, p.author, p.categories, p.published_at, p.updated_at, COALESCE(p.published_at, p.created_at), COALESCE(ps.is_read, false), EXISTS (SELECT 1 FROM saved_items si WHERE si.post_id = p.id AND si.user_id = $2), s.id, COALESCE(NULLIF(fs.title, ''), s.title), s.url, s.site_url, s.image_url FROM posts p JOIN sources s ON s.id = p.source_id JOIN feed_source fs ON fs.source_id = p.source_id LEFT JOIN post_states ps ON ps.post_id = p.id AND ps.user_id = $2 WHERE fs.feed_id = $1 AND ($3 = false OR ps.is_read IS NOT TRUE)`
+)
+
+// Full-text search matches the generated search column, headlines are
+// generated from plain text with matched terms wrapped in highlight delimiters
+const (
+	searchSelect = `WITH q AS (SELECT websearch_to_tsquery('english', $1) AS query)
+SELECT p.id, p.title, p.description, p.url, p.author, p.categories, p.published_at, p.updated_at, COALESCE(p.published_at, p.created_at), s.id, %s, s.url, s.site_url, s.image_url,
+	ts_headline('english', p.title, q.query, '` + titleHeadlineOptions + `'),
+	ts_headline('english', regexp_replace(p.description, '<[^>]*>', ' ', 'g'), q.query, '` + descriptionHeadlineOptions + `')
+FROM q, posts p JOIN sources s ON s.id = p.source_id %s
+WHERE p.search @@ q.query AND %s
+ORDER BY ts_rank(p.search, q.query) DESC, ` + postOrderDesc + `
+LIMIT $2 OFFSET $3`
+
+	titleHeadlineOptions       = `StartSel=` + listing.HighlightStart + `, StopSel=` + listing.HighlightStop + `, HighlightAll=true`
+	descriptionHeadlineOptions = `StartSel=` + listing.HighlightStart + `, StopSel=` + listing.HighlightStop + `, MaxFragments=2, MaxWords=25, MinWords=10, FragmentDelimiter=" … "`
+)
+
+var (
+	searchFeedPostsQuery   = fmt.Sprintf(searchSelect, `COALESCE(NULLIF(fs.title, ''), s.title)`, `JOIN feed_source fs ON fs.source_id = p.source_id`, `fs.feed_id = $4`)
+	searchUserPostsQuery   = fmt.Sprintf(searchSelect, `s.title`, ``, `p.source_id IN (SELECT fs.source_id FROM feed_source fs JOIN feeds f ON f.id = fs.feed_id WHERE f.user_id = $4)`)
+	searchPublicPostsQuery = fmt.Sprintf(searchSelect, `s.title`, ``, `p.source_id IN (SELECT fs.source_id FROM feed_source fs JOIN feeds f ON f.id = fs.feed_id WHERE f.is_public)`)
+)
+
+const (
+	addPostQuery              = `INSERT INTO posts (source_id, guid, title, description, url, author, categories, published_at, updated_at) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9) RETURNING id`
+	getSourcePostsQuery       = sourcePostsSelect + ` ORDER BY ` + postOrderDesc + ` LIMIT $2`
+	getSourcePostsBeforeQuery = sourcePostsSelect + ` AND (` + postKey + `) < ($3, $4) ORDER BY ` + postOrderDesc + ` LIMIT $2`
+	getSourcePostsAfterQuery  = sourcePostsSelect + ` AND (` + postKey + `) > ($3, $4) ORDER BY ` + postOrderAsc + ` LIMIT $2`
+	getFeedPostsQuery         = feedPostsSelect + ` ORDER BY ` + postOrderDesc + ` LIMIT $4`
+	getFeedPostsBeforeQuery   = feedPostsSelect + ` AND (` + postKey + `) < ($5, $6) ORDER BY ` + postOrderDesc + ` LIMIT $4`
+	getFeedPostsAfterQuery    = feedPostsSelect + ` AND (` + postKey + `) > ($5, $6) ORDER BY ` + postOrderAsc + ` LIMIT $4`
 	removeSourcePostQuery     = `DELETE FROM posts WHERE source_id = $1 AND id = $2`
 	removeAllSourcePostsQuery = `DELETE FROM posts WHERE source_id = $1`
 	updateSourcePostQuery     = `UPDATE posts SET title = $1, description = $2, url = $3, published_at = $4, updated_at = $5 WHERE source_id = $6 AND id = $7`
+	getUnreadCountsQuery      = `SELECT f.id, count(p.id) FROM feeds f JOIN feed_source fs ON fs.feed_id = f.id JOIN posts p ON p.source_id = fs.source_id LEFT JOIN post_states ps ON ps.post_id = p.id AND ps.user_id = $1 WHERE f.user_id = $1 AND ps.is_read IS NOT TRUE GROUP BY f.id`
+	markPostReadQuery         = `INSERT INTO post_states (user_id, post_id, is_read) SELECT $1, p.id, $3 FROM posts p WHERE p.id = $2 AND p.source_id IN (SELECT fs.source_id FROM feed_source fs JOIN feeds f ON f.id = fs.feed_id WHERE f.is_public OR f.user_id = $1) ON CONFLICT (user_id, post_id) DO UPDATE SET is_read = EXCLUDED.is_read, updated_at = CURRENT_TIMESTAMP`
+	markFeedReadQuery         = `INSERT INTO post_states (user_id, post_id, is_read) SELECT $1, p.id, true FROM posts p JOIN feed_source fs ON fs.source_id = p.source_id WHERE fs.feed_id = $2 AND ($3::timestamp IS NULL OR COALESCE(p.published_at, p.created_at) < $3)
+ON CONFLICT (user_id, post_id) DO UPDATE SET is_read = true, updated_at = CURRENT_TIMESTAMP WHERE post_states.is_read = false`
+
+	// Effective limits of a source are the most permissive limits among
+	// feeds including it. Posts still present in the source, which are the
+	// newest item_count posts, are kept regardless of the limits.
+	removeExpiredPostsQuery = `WITH policy AS (
+	SELECT fs.source_id,
+		CASE WHEN bool_or(COALESCE(f.retention_days, $1::int) = 0) THEN 0 ELSE max(COALESCE(f.retention_days, $1::int)) END AS max_days,
+		CASE WHEN bool_or(COALESCE(f.retention_posts, $2::int) = 0) THEN 0 ELSE max(COALESCE(f.retention_posts, $2::int)) END AS max_posts
+	FROM feed_source fs JOIN feeds f ON f.id = fs.feed_id
+	GROUP BY fs.source_id
+), ranked AS (
+	SELECT p.id, p.source_id, COALESCE(p.published_at, p.created_at) AS at,
+		row_number() OVER (PARTITION BY p.source_id ORDER BY COALESCE(p.published_at, p.created_at) DESC, p.id DESC) AS position
+	FROM posts p
+)
+DELETE FROM posts WHERE id IN (
+	SELECT r.id FROM ranked r
+	JOIN policy ON policy.source_id = r.source_id
+	JOIN sources s ON s.id = r.source_id
+	WHERE r.position > s.item_count AND (
+		(policy.max_days > 0 AND r.at < $3::timestamp - make_interval(days => policy.max_days)) OR
+		(policy.max_posts > 0 AND r.position > policy.max_posts)
+	)
+)`
 )
 
 func newPostRepository(c *Connection) (r *postRepository, err error) {
@@ -33,17 +111,28 @@ func newPostRepository(c *Connection) (r *postRepository, err error) {
 	err = c.Batch().
 		Prepare(addPostQuery, &r.addPostStmt).
 		Prepare(getSourcePostsQuery, &r.getSourcePostsStmt).
+		Prepare(getSourcePostsBeforeQuery, &r.getSourcePostsBeforeStmt).
+		Prepare(getSourcePostsAfterQuery, &r.getSourcePostsAfterStmt).
 		Prepare(getFeedPostsQuery, &r.getFeedPostsStmt).
+		Prepare(getFeedPostsBeforeQuery, &r.getFeedPostsBeforeStmt).
+		Prepare(getFeedPostsAfterQuery, &r.getFeedPostsAfterStmt).
 		Prepare(removeSourcePostQuery, &r.removeSourcePostStmt).
 		Prepare(removeAllSourcePostsQuery, &r.removeAllSourcePostsStmt).
 		Prepare(updateSourcePostQuery, &r.updateSourcePostStmt).
+		Prepare(removeExpiredPostsQuery, &r.removeExpiredPostsStmt).
+		Prepare(searchFeedPostsQuery, &r.searchFeedPostsStmt).
+		Prepare(searchUserPostsQuery, &r.searchUserPostsStmt).
+		Prepare(searchPublicPostsQuery, &r.searchPublicPostsStmt).
+		Prepare(getUnreadCountsQuery, &r.getUnreadCountsStmt).
+		Prepare(markPostReadQuery, &r.markPostReadStmt).
+		Prepare(markFeedReadQuery, &r.markFeedReadStmt).
 		Exec()
 	return
 }
 
 func (r *postRepository) AddPost(data adding.PostData) (adding.Post, error) {
 	var id int
-	err := r.addPostStmt.QueryRow(data.SourceId, data.Title, data.Description, data.Url, data.PublishedAt, data.UpdatedAt).Scan(&id)
+	err := r.addPostStmt.QueryRow(data.SourceId, data.Guid, data.Title, data.Description, data.Url, data.Author, categoriesArray(data.Categories), data.PublishedAt, data.UpdatedAt).Scan(&id)
 	if err != nil {
 		return nil, err
 	}
@@ -52,66 +141,197 @@ func (r *postRepository) AddPost(data adding.PostData) (adding.Post, error) {
 		title:       data.Title,
 		description: data.Description,
 		url:         data.Url,
+		author:      data.Author,
+		categories:  data.Categories,
 		publishedAt: data.PublishedAt,
 		updatedAt:   data.UpdatedAt,
 	}, nil
 }
 
 func (r *postRepository) AddManyPosts(items ...adding.PostData) error {
+	values, params := postValues(items)
+	query := fmt.Sprintf(`INSERT INTO posts (source_id, guid, title, description, url, author, categories, published_at, updated_at) VALUES %s`, values)
+	_, err := r.c.db.Exec(query, params...)
+	return err
+}
+
+func (r *postRepository) UpsertPosts(items ...adding.PostData) error {
+	if len(items) == 0 {
+		return nil
+	}
+
+	// Posts stored before items had GUIDs are keyed by their links, they're
+	// rekeyed by GUIDs of the matching items instead of being duplicated
+	var rekeyValues string
+	rekeyParams := make([]interface{}, 0, 3*len(items))
+	for i, item := range items {
+		if i > 0 {
+			rekeyValues += ", "
+		}
+		rekeyValues += fmt.Sprintf("($%v::int, $%v::text, $%v::text)", i*3+1, i*3+2, i*3+3)
+		rekeyParams = append(rekeyParams, item.SourceId, item.Guid, item.Url)
+	}
+	rekeyQuery := fmt.Sprintf(`UPDATE posts SET guid = v.guid FROM (VALUES %s) AS v (source_id, guid, url)
+WHERE posts.source_id = v.source_id AND posts.url = v.url AND posts.guid = posts.url AND v.guid <> v.url
+AND NOT EXISTS (SELECT 1 FROM posts p WHERE p.source_id = v.source_id AND p.guid = v.guid)`, rekeyValues)
+
+	// Existing posts are only touched when any of the fields has changed
+	values, params := postValues(items)
+	query := fmt.Sprintf(`INSERT INTO posts (source_id, guid, title, description, url, author, categories, published_at, updated_at) VALUES %s
+ON CONFLICT (source_id, guid) DO UPDATE SET title = EXCLUDED.title, description = EXCLUDED.description, url = EXCLUDED.url, author = EXCLUDED.author, categories = EXCLUDED.categories, published_at = EXCLUDED.published_at, updated_at = EXCLUDED.updated_at
+WHERE (posts.title, posts.description, posts.url, posts.author, posts.categories, posts.published_at, posts.updated_at) IS DISTINCT FROM (EXCLUDED.title, EXCLUDED.description, EXCLUDED.url, EXCLUDED.author, EXCLUDED.categories, EXCLUDED.published_at, EXCLUDED.updated_at)`, values)
+
+	tx, err := r.c.db.Begin()
+	if err != nil {
+		return err
+	}
+	if _, err := tx.Exec(rekeyQuery, rekeyParams...); err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+	if _, err := tx.Exec(query, params...); err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+// postValues builds VALUES list and its parameters for inserting given posts
+func postValues(items []adding.PostData) (string, []interface{}) {
+	const columns = 9
 	var query string
-	params := make([]interface{}, 6*len(items))
+	params := make([]interface{}, columns*len(items))
 	for i, item := range items {
 		if i > 0 {
 			query += ", "
 		}
-		query += fmt.Sprintf("($%v, $%v, $%v, $%v, $%v, $%v)", i*6+1, i*6+2, i*6+3, i*6+4, i*6+5, i*6+6)
-		params := params[i*6:]
+		query += "("
+		for j := 1; j <= columns; j++ {
+			if j > 1 {
+				query += ", "
+			}
+			query += fmt.Sprintf("$%v", i*columns+j)
+		}
+		query += ")"
+		params := params[i*columns:]
 		params[0] = item.SourceId
-		params[1] = item.Title
-		params[2] = item.Description
-		params[3] = item.Url
-		params[4] = item.PublishedAt
-		params[5] = item.UpdatedAt
+		params[1] = item.Guid
+		params[2] = item.Title
+		params[3] = item.Description
+		params[4] = item.Url
+		params[5] = item.Author
+		params[6] = categoriesArray(item.Categories)
+		params[7] = item.PublishedAt
+		params[8] = item.UpdatedAt
 	}
-	query = fmt.Sprintf(`INSERT INTO posts (source_id, title, description, url, published_at, updated_at) VALUES %s`, query)
-	_, err := r.c.db.Exec(query, params...)
-	return err
+	return query, params
 }
 
-func (r *postRepository) GetSourcePosts(sourceId int) ([]listing.Post, error) {
-	rows, err := r.getSourcePostsStmt.Query(sourceId)
-	defer rows.Close()
+// categoriesArray returns array parameter of the categories, nil slices are
+// stored as empty arrays
+func categoriesArray(categories []string) interface{} {
+	if categories == nil {
+		categories = []string{}
+	}
+	return pq.Array(categories)
+}
+
+// queryPage runs one of the statements selecting the page of posts, limit
+// and cursor params follow the given params
+func queryPage(first, before, after *sql.Stmt, page listing.Page, params ...interface{}) (*sql.Rows, error) {
+	params = append(params, page.Limit)
+	switch {
+	case page.Before != nil:
+		return before.Query(append(params, page.Before.Time, page.Before.Id)...)
+	case page.After != nil:
+		return after.Query(append(params, page.After.Time, page.After.Id)...)
+	default:
+		return first.Query(params...)
+	}
+}
+
+func (r *postRepository) GetSourcePosts(sourceId int, page listing.Page) ([]listing.Post, error) {
+	rows, err := queryPage(r.getSourcePostsStmt, r.getSourcePostsBeforeStmt, r.getSourcePostsAfterStmt, page, sourceId)
 	if err != nil {
 		return nil, err
 	}
+	defer rows.Close()
 	var result []listing.Post
 	for rows.Next() {
 		var p post
-		err := rows.Scan(&p.id, &p.title, &p.description, &p.url, &p.publishedAt, &p.updatedAt)
+		err := rows.Scan(&p.id, &p.title, &p.description, &p.url, &p.author, pq.Array(&p.categories), &p.publishedAt, &p.updatedAt, &p.sortedAt)
 		if err != nil {
 			return nil, err
 		}
 		result = append(result, &p)
 	}
-	return result, nil
+	if page.After != nil {
+		reverse(len(result), func(i, j int) { result[i], result[j] = result[j], result[i] })
+	}
+	return result, rows.Err()
 }
 
-func (r *postRepository) GetFeedPosts(feedId int) ([]listing.SourcePost, error) {
-	rows, err := r.getFeedPostsStmt.Query(feedId)
-	defer rows.Close()
+func (r *postRepository) GetFeedPosts(feedId int, page listing.Page) ([]listing.SourcePost, *listing.Cursor, error) {
+	rows, err := queryPage(r.getFeedPostsStmt, r.getFeedPostsBeforeStmt, r.getFeedPostsAfterStmt, page, feedId, page.UserId, page.Unread)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
+	defer rows.Close()
 	var result []listing.SourcePost
 	for rows.Next() {
 		var p sourcePost
-		err := rows.Scan(&p.id, &p.title, &p.description, &p.url, &p.publishedAt, &p.updatedAt, &p.source.id, &p.source.title, &p.source.url)
+		err := rows.Scan(&p.id, &p.title, &p.description, &p.url, &p.author, pq.Array(&p.categories), &p.publishedAt, &p.updatedAt, &p.sortedAt, &p.read, &p.saved, &p.source.id, &p.source.title, &p.source.url, &p.source.siteUrl, &p.source.imageUrl)
+		if err != nil {
+			return nil, nil, err
+		}
+		result = append(result, &p)
+	}
+	if page.After != nil {
+		reverse(len(result), func(i, j int) { result[i], result[j] = result[j], result[i] })
+	}
+	return result, nil, rows.Err()
+}
+
+func (r *postRepository) SearchPosts(search listing.Search) ([]listing.SearchResult, error) {
+	var rows *sql.Rows
+	var err error
+	switch search.Scope {
+	case listing.SearchFeed:
+		rows, err = r.searchFeedPostsStmt.Query(search.Query, search.Limit, search.Offset, search.FeedId)
+	case listing.SearchUser:
+		rows, err = r.searchUserPostsStmt.Query(search.Query, search.Limit, search.Offset, search.UserId)
+	case listing.SearchPublic:
+		rows, err = r.searchPublicPostsStmt.Query(search.Query, search.Limit, search.Offset)
+	default:
+		return nil, fmt.Errorf("unknown search scope '%s'", search.Scope)
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var result []listing.SearchResult
+	for rows.Next() {
+		var p searchResult
+		err := rows.Scan(&p.id, &p.title, &p.description, &p.url, &p.author, pq.Array(&p.categories), &p.publishedAt, &p.updatedAt, &p.sortedAt, &p.source.id, &p.source.title, &p.source.url, &p.source.siteUrl, &p.source.imageUrl, &p.titleHeadline, &p.descriptionHeadline)
 		if err != nil {
 			return nil, err
 		}
+		// Tags are stripped from the description by the query, entities are
+		// decoded here, so headlines are plain text and escaped once when
+		// displayed
+		p.descriptionHeadline = html.UnescapeString(p.descriptionHeadline)
 		result = append(result, &p)
 	}
-	return result, nil
+	return result, rows.Err()
+}
+
+// reverse reverses order of n items using the swap function, pages of newer
+// posts are queried in ascending order
+func reverse(n int, swap func(i, j int)) {
+	for i, j := 0, n-1; i < j; i, j = i+1, j-1 {
+		swap(i, j)
+	}
 }
 
 func (r *postRepository) RemoveSourcePost(sourceId int, postId int) error {
@@ -124,18 +344,66 @@ func (r *postRepository) RemoveAllSourcePosts(sourceId int) error {
 	return err
 }
 
+func (r *postRepository) RemoveExpiredPosts(defaults removing.Retention, now time.Time) (int64, error) {
+	result, err := r.removeExpiredPostsStmt.Exec(defaults.MaxAgeDays, defaults.MaxPosts, now.UTC())
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
 func (r *postRepository) UpdateSourcePost(sourceId int, postId int, data updating.Post) error {
 	_, err := r.updateSourcePostStmt.Exec(data.Title, data.Description, data.Url, data.PublishedAt, data.UpdatedAt, sourceId, postId)
 	return err
 }
 
+func (r *postRepository) GetUnreadCounts(userId string) (map[int]int, error) {
+	rows, err := r.getUnreadCountsStmt.Query(userId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	result := make(map[int]int)
+	for rows.Next() {
+		var feedId, count int
+		if err := rows.Scan(&feedId, &count); err != nil {
+			return nil, err
+		}
+		result[feedId] = count
+	}
+	return result, rows.Err()
+}
+
+func (r *postRepository) MarkPostRead(userId string, postId int, read bool) error {
+	result, err := r.markPostReadStmt.Exec(userId, postId, read)
+	if err != nil {
+		return err
+	}
+	if n, err := result.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return listing.ErrNotFound
+	}
+	return nil
+}
+
+func (r *postRepository) MarkFeedRead(userId string, feedId int, before *time.Time) error {
+	_, err := r.markFeedReadStmt.Exec(userId, feedId, before)
+	return err
+}
+
 type post struct {
 	id          int
 	title       string
 	description string
 	url         string
+	author      string
+	categories  []string
 	publishedAt *time.Time
 	updatedAt   *time.Time
+
+	// sortedAt is the publish time falling back to creation time
+	sortedAt time.Time
 }
 
 func (p *post) Id() int {
@@ -154,6 +422,14 @@ func (p *post) Url() string {
 	return p.url
 }
 
+func (p *post) Author() string {
+	return p.author
+}
+
+func (p *post) Categories() []string {
+	return p.categories
+}
+
 func (p *post) PublishedAt() *time.Time {
 	return p.publishedAt
 }
@@ -162,11 +438,39 @@ func (p *post) UpdatedAt() *time.Time {
 	return p.updatedAt
 }
 
+func (p *post) Cursor() listing.Cursor {
+	return listing.Cursor{Time: p.sortedAt, Id: p.id}
+}
+
 type sourcePost struct {
 	post
 	source source
+	read   bool
+	saved  bool
+}
+
+func (p *sourcePost) Read() bool {
+	return p.read
+}
+
+func (p *sourcePost) Saved() bool {
+	return p.saved
 }
 
 func (p *sourcePost) Source() listing.Source {
 	return &p.source
 }
+
+type searchResult struct {
+	sourcePost
+	titleHeadline       string
+	descriptionHeadline string
+}
+
+func (p *searchResult) TitleHeadline() string {
+	return p.titleHeadline
+}
+
+func (p *searchResult) DescriptionHeadline() string {
+	return p.descriptionHeadline
+}
@@ -0,0 +1,148 @@
+package postgres
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/themisir/myfeed/pkg/listing"
+)
+
+type savedItemRepository struct {
+	c                       *Connection
+	savePostStmt            *sql.Stmt
+	getSavedItemsStmt       *sql.Stmt
+	getSavedItemsBeforeStmt *sql.Stmt
+	getSavedItemsAfterStmt  *sql.Stmt
+	removeSavedItemStmt     *sql.Stmt
+	removeSavedPostStmt     *sql.Stmt
+}
+
+const (
+	savedItemsSelect    = `SELECT id, post_id, title, description, url, author, source_title, source_url, site_url, published_at, saved_at FROM saved_items WHERE user_id = $1`
+	savedItemOrderDesc  = `saved_at DESC, id DESC`
+	savedItemOrderAsc   = `saved_at, id`
+	savedItemLimit      = ` LIMIT NULLIF($2::int, 0)`
+	savePostQuery       = `WITH inserted AS (INSERT INTO saved_items (user_id, post_id, title, description, url, author, source_title, source_url, site_url, published_at) SELECT $1, p.id, p.title, p.description, p.url, p.author, s.title, s.url, s.site_url, p.published_at FROM posts p JOIN sources s ON s.id = p.source_id WHERE p.id = $2 AND p.source_id IN (SELECT fs.source_id FROM feed_source fs JOIN feeds f ON f.id = fs.feed_id WHERE f.is_public OR f.user_id = $1) ON CONFLICT (user_id, post_id) DO NOTHING RETURNING id) SELECT EXISTS (SELECT 1 FROM inserted) OR EXISTS (SELECT 1 FROM saved_items WHERE user_id = $1 AND post_id = $2)`
+	getSavedItemsQuery  = savedItemsSelect + ` ORDER BY ` + savedItemOrderDesc + savedItemLimit
+	getSavedBeforeQuery = savedItemsSelect + ` AND (saved_at, id) < ($3, $4) ORDER BY ` + savedItemOrderDesc + savedItemLimit
+	getSavedAfterQuery  = savedItemsSelect + ` AND (saved_at, id) > ($3, $4) ORDER BY ` + savedItemOrderAsc + savedItemLimit
+	removeSavedItem     = `DELETE FROM saved_items WHERE user_id = $1 AND id = $2`
+	removeSavedPost     = `DELETE FROM saved_items WHERE user_id = $1 AND post_id = $2`
+)
+
+func newSavedItemRepository(c *Connection) (r *savedItemRepository, err error) {
+	r = &savedItemRepository{c: c}
+	err = c.Batch().
+		Prepare(savePostQuery, &r.savePostStmt).
+		Prepare(getSavedItemsQuery, &r.getSavedItemsStmt).
+		Prepare(getSavedBeforeQuery, &r.getSavedItemsBeforeStmt).
+		Prepare(getSavedAfterQuery, &r.getSavedItemsAfterStmt).
+		Prepare(removeSavedItem, &r.removeSavedItemStmt).
+		Prepare(removeSavedPost, &r.removeSavedPostStmt).
+		Exec()
+	return
+}
+
+func (r *savedItemRepository) SavePost(userId string, postId int) error {
+	var saved bool
+	if err := r.savePostStmt.QueryRow(userId, postId).Scan(&saved); err != nil {
+		return err
+	}
+	if !saved {
+		return listing.ErrNotFound
+	}
+	return nil
+}
+
+func (r *savedItemRepository) GetSavedItems(userId string, page listing.Page) ([]listing.SavedItem, error) {
+	rows, err := queryPage(r.getSavedItemsStmt, r.getSavedItemsBeforeStmt, r.getSavedItemsAfterStmt, page, userId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var result []listing.SavedItem
+	for rows.Next() {
+		var i savedItem
+		err := rows.Scan(&i.id, &i.postId, &i.title, &i.description, &i.url, &i.author, &i.sourceTitle, &i.sourceUrl, &i.siteUrl, &i.publishedAt, &i.savedAt)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, &i)
+	}
+	if page.After != nil {
+		reverse(len(result), func(i, j int) { result[i], result[j] = result[j], result[i] })
+	}
+	return result, rows.Err()
+}
+
+func (r *savedItemRepository) RemoveSavedItem(userId string, itemId int) error {
+	_, err := r.removeSavedItemStmt.Exec(userId, itemId)
+	return err
+}
+
+func (r *savedItemRepository) RemoveSavedPost(userId string, postId int) error {
+	_, err := r.removeSavedPostStmt.Exec(userId, postId)
+	return err
+}
+
+type savedItem struct {
+	id          int
+	postId      *int
+	title       string
+	description string
+	url         string
+	author      string
+	sourceTitle string
+	sourceUrl   string
+	siteUrl     string
+	publishedAt *time.Time
+	savedAt     time.Time
+}
+
+func (i *savedItem) Id() int {
+	return i.id
+}
+
+func (i *savedItem) PostId() *int {
+	return i.postId
+}
+
+func (i *savedItem) Title() string {
+	return i.title
+}
+
+func (i *savedItem) Description() string {
+	return i.description
+}
+
+func (i *savedItem) Url() string {
+	return i.url
+}
+
+func (i *savedItem) Author() string {
+	return i.author
+}
+
+func (i *savedItem) SourceTitle() string {
+	return i.sourceTitle
+}
+
+func (i *savedItem) SourceUrl() string {
+	return i.sourceUrl
+}
+
+func (i *savedItem) SiteUrl() string {
+	return i.siteUrl
+}
+
+func (i *savedItem) PublishedAt() *time.Time {
+	return i.publishedAt
+}
+
+func (i *savedItem) SavedAt() time.Time {
+	return i.savedAt
+}
+
+func (i *savedItem) Cursor() listing.Cursor {
+	return listing.Cursor{Time: i.savedAt, Id: i.id}
+}
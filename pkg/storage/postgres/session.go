@@ -0,0 +1,136 @@
+package postgres
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/themisir/myfeed/pkg/adding"
+	"github.com/themisir/myfeed/pkg/listing"
+)
+
+type sessionRepository struct {
+	c                       *Connection
+	addSessionStmt          *sql.Stmt
+	removeExpiredStmt       *sql.Stmt
+	getSessionStmt          *sql.Stmt
+	getUserSessionsStmt     *sql.Stmt
+	touchSessionStmt        *sql.Stmt
+	removeUserSessionStmt   *sql.Stmt
+	removeOtherSessionsStmt *sql.Stmt
+}
+
+const sessionColumns = `id, user_id, ip, user_agent, created_at, last_seen_at, expires_at`
+
+const (
+	addSessionQuery          = `INSERT INTO sessions (id, user_id, ip, user_agent, created_at, last_seen_at, expires_at) VALUES ($1, $2, $3, $4, $5, $5, $6)`
+	removeExpiredSessions    = `DELETE FROM sessions WHERE user_id = $1 AND expires_at <= $2`
+	getSessionQuery          = `SELECT ` + sessionColumns + ` FROM sessions WHERE id = $1 AND expires_at > $2`
+	getUserSessionsQuery     = `SELECT ` + sessionColumns + ` FROM sessions WHERE user_id = $1 AND expires_at > $2 ORDER BY last_seen_at DESC`
+	touchSessionQuery        = `UPDATE sessions SET last_seen_at = $1, ip = $2 WHERE id = $3`
+	removeUserSessionQuery   = `DELETE FROM sessions WHERE user_id = $1 AND id = $2`
+	removeOtherSessionsQuery = `DELETE FROM sessions WHERE user_id = $1 AND id <> $2`
+)
+
+func newSessionRepository(c *Connection) (r *sessionRepository, err error) {
+	r = &sessionRepository{c: c}
+	err = c.Batch().
+		Prepare(addSessionQuery, &r.addSessionStmt).
+		Prepare(removeExpiredSessions, &r.removeExpiredStmt).
+		Prepare(getSessionQuery, &r.getSessionStmt).
+		Prepare(getUserSessionsQuery, &r.getUserSessionsStmt).
+		Prepare(touchSessionQuery, &r.touchSessionStmt).
+		Prepare(removeUserSessionQuery, &r.removeUserSessionStmt).
+		Prepare(removeOtherSessionsQuery, &r.removeOtherSessionsStmt).
+		Exec()
+	return
+}
+
+func (r *sessionRepository) AddSession(data adding.SessionData) error {
+	if _, err := r.removeExpiredStmt.Exec(data.UserId, data.CreatedAt.UTC()); err != nil {
+		return err
+	}
+	_, err := r.addSessionStmt.Exec(data.Id, data.UserId, data.IP, data.UserAgent, data.CreatedAt.UTC(), data.ExpiresAt.UTC())
+	return err
+}
+
+func (r *sessionRepository) GetSession(id string, now time.Time) (listing.Session, error) {
+	var s session
+	err := r.getSessionStmt.QueryRow(id, now.UTC()).Scan(&s.id, &s.userId, &s.ip, &s.userAgent, &s.createdAt, &s.lastSeenAt, &s.expiresAt)
+	if err == sql.ErrNoRows {
+		return nil, listing.ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+func (r *sessionRepository) GetUserSessions(userId string, now time.Time) ([]listing.Session, error) {
+	rows, err := r.getUserSessionsStmt.Query(userId, now.UTC())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var result []listing.Session
+	for rows.Next() {
+		var s session
+		if err := rows.Scan(&s.id, &s.userId, &s.ip, &s.userAgent, &s.createdAt, &s.lastSeenAt, &s.expiresAt); err != nil {
+			return nil, err
+		}
+		result = append(result, &s)
+	}
+	return result, rows.Err()
+}
+
+func (r *sessionRepository) TouchSession(id string, seenAt time.Time, ip string) error {
+	_, err := r.touchSessionStmt.Exec(seenAt.UTC(), ip, id)
+	return err
+}
+
+func (r *sessionRepository) RemoveUserSession(userId string, id string) error {
+	_, err := r.removeUserSessionStmt.Exec(userId, id)
+	return err
+}
+
+func (r *sessionRepository) RemoveOtherSessions(userId string, keepId string) error {
+	_, err := r.removeOtherSessionsStmt.Exec(userId, keepId)
+	return err
+}
+
+type session struct {
+	id         string
+	userId     string
+	ip         string
+	userAgent  string
+	createdAt  time.Time
+	lastSeenAt time.Time
+	expiresAt  time.Time
+}
+
+func (s *session) Id() string {
+	return s.id
+}
+
+func (s *session) UserId() string {
+	return s.userId
+}
+
+func (s *session) IP() string {
+	return s.ip
+}
+
+func (s *session) UserAgent() string {
+	return s.userAgent
+}
+
+func (s *session) CreatedAt() time.Time {
+	return s.createdAt
+}
+
+func (s *session) LastSeenAt() time.Time {
+	return s.lastSeenAt
+}
+
+func (s *session) ExpiresAt() time.Time {
+	return s.expiresAt
+}
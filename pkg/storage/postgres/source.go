@@ -2,6 +2,8 @@ package postgres
 
 import (
 	"database/sql"
+	"time"
+
 	"github.com/themisir/myfeed/pkg/adding"
 	"github.com/themisir/myfeed/pkg/listing"
 	"github.com/themisir/myfeed/pkg/updating"
@@ -12,22 +14,32 @@ type sourceRepository struct {
 	addSourceStmt          *sql.Stmt
 	getSourceStmt          *sql.Stmt
 	getSourcesStmt         *sql.Stmt
+	getDueSourcesStmt      *sql.Stmt
 	getFeedSourcesStmt     *sql.Stmt
 	findSourceByUrlStmt    *sql.Stmt
 	removeSourceStmt       *sql.Stmt
 	removeEmptySourcesStmt *sql.Stmt
 	updateSourceStmt       *sql.Stmt
+	scheduleSourceStmt     *sql.Stmt
+	recordSuccessStmt      *sql.Stmt
+	recordFailureStmt      *sql.Stmt
 }
 
+const sourceColumns = `id, title, url, description, site_url, image_url, etag, last_modified, next_fetch_at, last_success_at, last_error, last_status, failure_count, diagnostics`
+
 const (
 	addSourceQuery          = `INSERT INTO sources (title, url) VALUES ($1, $2) RETURNING id`
-	getSourceQuery          = `SELECT id, title, url FROM sources WHERE id = $1`
-	getSourcesQuery         = `SELECT id, title, url FROM sources`
-	getFeedSourcesQuery     = `SELECT id, title, url FROM sources JOIN feed_source fs ON fs.source_id = sources.id WHERE fs.feed_id = $1`
-	findSourceByUrlQuery    = `SELECT id, title, url FROM sources WHERE url = $1`
+	getSourceQuery          = `SELECT ` + sourceColumns + ` FROM sources WHERE id = $1`
+	getSourcesQuery         = `SELECT ` + sourceColumns + ` FROM sources`
+	getDueSourcesQuery      = `SELECT ` + sourceColumns + ` FROM sources WHERE next_fetch_at <= $1 ORDER BY next_fetch_at`
+	getFeedSourcesQuery     = `SELECT s.id, s.title, s.url, s.description, s.site_url, s.image_url, s.etag, s.last_modified, s.next_fetch_at, s.last_success_at, s.last_error, s.last_status, s.failure_count, s.diagnostics, fs.title FROM sources s JOIN feed_source fs ON fs.source_id = s.id WHERE fs.feed_id = $1`
+	findSourceByUrlQuery    = `SELECT ` + sourceColumns + ` FROM sources WHERE url = $1`
 	removeSource            = `DELETE FROM sources WHERE id = $1`
 	removeEmptySourcesQuery = `WITH s AS (SELECT source_id AS id FROM feed_source) DELETE FROM sources WHERE id NOT IN (SELECT id FROM s)`
-	updateSource            = `UPDATE sources SET title = $1 WHERE id = $2`
+	updateSource            = `UPDATE sources SET title = $1, description = $2, site_url = $3, image_url = $4, diagnostics = $5, etag = $6, last_modified = $7, item_count = $8 WHERE id = $9`
+	scheduleSource          = `UPDATE sources SET next_fetch_at = $1 WHERE id = $2`
+	recordSourceSuccess     = `UPDATE sources SET last_success_at = $1, last_status = $2, last_error = '', failure_count = 0 WHERE id = $3`
+	recordSourceFailure     = `UPDATE sources SET last_status = $1, last_error = $2, failure_count = failure_count + 1 WHERE id = $3 RETURNING failure_count`
 )
 
 func newSourceRepository(c *Connection) (r *sourceRepository, err error) {
@@ -36,11 +48,15 @@ func newSourceRepository(c *Connection) (r *sourceRepository, err error) {
 		Prepare(addSourceQuery, &r.addSourceStmt).
 		Prepare(getSourceQuery, &r.getSourceStmt).
 		Prepare(getSourcesQuery, &r.getSourcesStmt).
+		Prepare(getDueSourcesQuery, &r.getDueSourcesStmt).
 		Prepare(getFeedSourcesQuery, &r.getFeedSourcesStmt).
 		Prepare(findSourceByUrlQuery, &r.findSourceByUrlStmt).
 		Prepare(removeSource, &r.removeSourceStmt).
 		Prepare(removeEmptySourcesQuery, &r.removeEmptySourcesStmt).
 		Prepare(updateSource, &r.updateSourceStmt).
+		Prepare(scheduleSource, &r.scheduleSourceStmt).
+		Prepare(recordSourceSuccess, &r.recordSuccessStmt).
+		Prepare(recordSourceFailure, &r.recordFailureStmt).
 		Exec()
 	return
 }
@@ -60,7 +76,7 @@ func (r *sourceRepository) AddSource(data adding.SourceData) (adding.Source, err
 
 func (r *sourceRepository) scanRow(row *sql.Row) (listing.Source, error) {
 	var s source
-	err := row.Scan(&s.id, &s.title, &s.url)
+	err := row.Scan(&s.id, &s.title, &s.url, &s.description, &s.siteUrl, &s.imageUrl, &s.etag, &s.lastModified, &s.nextFetchAt, &s.lastSuccessAt, &s.lastError, &s.lastStatus, &s.failureCount, &s.diagnostics)
 	if err != nil {
 		return nil, err
 	}
@@ -71,7 +87,7 @@ func (r *sourceRepository) scanRows(rows *sql.Rows) ([]listing.Source, error) {
 	var sources []listing.Source
 	for rows.Next() {
 		var s source
-		if err := rows.Scan(&s.id, &s.title, &s.url); err != nil {
+		if err := rows.Scan(&s.id, &s.title, &s.url, &s.description, &s.siteUrl, &s.imageUrl, &s.etag, &s.lastModified, &s.nextFetchAt, &s.lastSuccessAt, &s.lastError, &s.lastStatus, &s.failureCount, &s.diagnostics); err != nil {
 			return nil, err
 		}
 		sources = append(sources, &s)
@@ -92,13 +108,30 @@ func (r *sourceRepository) GetSources() ([]listing.Source, error) {
 	return r.scanRows(rows)
 }
 
-func (r *sourceRepository) GetFeedSources(feedId int) ([]listing.Source, error) {
+func (r *sourceRepository) GetDueSources(now time.Time) ([]listing.Source, error) {
+	rows, err := r.getDueSourcesStmt.Query(now.UTC())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	return r.scanRows(rows)
+}
+
+func (r *sourceRepository) GetFeedSources(feedId int) ([]listing.FeedSource, error) {
 	rows, err := r.getFeedSourcesStmt.Query(feedId)
 	defer rows.Close()
 	if err != nil {
 		return nil, err
 	}
-	return r.scanRows(rows)
+	var sources []listing.FeedSource
+	for rows.Next() {
+		var s feedSource
+		if err := rows.Scan(&s.id, &s.title, &s.url, &s.description, &s.siteUrl, &s.imageUrl, &s.etag, &s.lastModified, &s.nextFetchAt, &s.lastSuccessAt, &s.lastError, &s.lastStatus, &s.failureCount, &s.diagnostics, &s.customTitle); err != nil {
+			return nil, err
+		}
+		sources = append(sources, &s)
+	}
+	return sources, nil
 }
 
 func (r *sourceRepository) FindSourceByUrl(url string) (listing.Source, error) {
@@ -116,14 +149,40 @@ func (r *sourceRepository) RemoveEmptySources() (err error) {
 }
 
 func (r *sourceRepository) UpdateSource(sourceId int, data updating.Source) (err error) {
-	_, err = r.updateSourceStmt.Exec(data.Title, sourceId)
+	_, err = r.updateSourceStmt.Exec(data.Title, data.Description, data.SiteUrl, data.ImageUrl, data.Diagnostics, data.ETag, data.LastModified, data.ItemCount, sourceId)
+	return
+}
+
+func (r *sourceRepository) ScheduleSource(sourceId int, nextFetchAt time.Time) (err error) {
+	_, err = r.scheduleSourceStmt.Exec(nextFetchAt.UTC(), sourceId)
+	return
+}
+
+func (r *sourceRepository) RecordSourceSuccess(sourceId int, status int, at time.Time) (err error) {
+	_, err = r.recordSuccessStmt.Exec(at.UTC(), status, sourceId)
+	return
+}
+
+func (r *sourceRepository) RecordSourceFailure(sourceId int, status int, message string) (failures int, err error) {
+	err = r.recordFailureStmt.QueryRow(status, message, sourceId).Scan(&failures)
 	return
 }
 
 type source struct {
-	id    int
-	title string
-	url   string
+	id            int
+	title         string
+	url           string
+	description   string
+	siteUrl       string
+	imageUrl      string
+	etag          string
+	lastModified  string
+	nextFetchAt   time.Time
+	lastSuccessAt *time.Time
+	lastError     string
+	lastStatus    int
+	failureCount  int
+	diagnostics   string
 }
 
 func (s *source) Id() int {
@@ -137,3 +196,56 @@ func (s *source) Title() string {
 func (s *source) Url() string {
 	return s.url
 }
+
+func (s *source) Description() string {
+	return s.description
+}
+
+func (s *source) SiteUrl() string {
+	return s.siteUrl
+}
+
+func (s *source) ImageUrl() string {
+	return s.imageUrl
+}
+
+func (s *source) ETag() string {
+	return s.etag
+}
+
+func (s *source) LastModified() string {
+	return s.lastModified
+}
+
+func (s *source) NextFetchAt() time.Time {
+	return s.nextFetchAt
+}
+
+func (s *source) LastSuccessAt() *time.Time {
+	return s.lastSuccessAt
+}
+
+func (s *source) LastError() string {
+	return s.lastError
+}
+
+func (s *source) LastStatus() int {
+	return s.lastStatus
+}
+
+func (s *source) FailureCount() int {
+	return s.failureCount
+}
+
+func (s *source) Diagnostics() string {
+	return s.diagnostics
+}
+
+type feedSource struct {
+	source
+	customTitle string
+}
+
+func (s *feedSource) CustomTitle() string {
+	return s.customTitle
+}
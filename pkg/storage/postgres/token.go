@@ -0,0 +1,118 @@
+package postgres
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/themisir/myfeed/pkg/adding"
+	"github.com/themisir/myfeed/pkg/listing"
+)
+
+type tokenRepository struct {
+	c                   *Connection
+	addTokenStmt        *sql.Stmt
+	findTokenByHashStmt *sql.Stmt
+	getUserTokensStmt   *sql.Stmt
+	touchTokenStmt      *sql.Stmt
+	removeUserTokenStmt *sql.Stmt
+}
+
+const tokenColumns = `id, user_id, name, scopes, created_at, last_used_at`
+
+const (
+	addTokenQuery        = `INSERT INTO access_tokens (user_id, name, token_hash, scopes) VALUES ($1, $2, $3, $4)`
+	findTokenByHashQuery = `SELECT ` + tokenColumns + ` FROM access_tokens WHERE token_hash = $1`
+	getUserTokensQuery   = `SELECT ` + tokenColumns + ` FROM access_tokens WHERE user_id = $1 ORDER BY created_at DESC`
+	touchTokenQuery      = `UPDATE access_tokens SET last_used_at = $1 WHERE id = $2`
+	removeUserTokenQuery = `DELETE FROM access_tokens WHERE user_id = $1 AND id = $2`
+)
+
+func newTokenRepository(c *Connection) (r *tokenRepository, err error) {
+	r = &tokenRepository{c: c}
+	err = c.Batch().
+		Prepare(addTokenQuery, &r.addTokenStmt).
+		Prepare(findTokenByHashQuery, &r.findTokenByHashStmt).
+		Prepare(getUserTokensQuery, &r.getUserTokensStmt).
+		Prepare(touchTokenQuery, &r.touchTokenStmt).
+		Prepare(removeUserTokenQuery, &r.removeUserTokenStmt).
+		Exec()
+	return
+}
+
+func (r *tokenRepository) AddToken(data adding.TokenData) error {
+	_, err := r.addTokenStmt.Exec(data.UserId, data.Name, data.Hash, pq.Array(data.Scopes))
+	return err
+}
+
+func (r *tokenRepository) FindTokenByHash(hash string) (listing.Token, error) {
+	var t token
+	err := r.findTokenByHashStmt.QueryRow(hash).Scan(&t.id, &t.userId, &t.name, pq.Array(&t.scopes), &t.createdAt, &t.lastUsedAt)
+	if err == sql.ErrNoRows {
+		return nil, listing.ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+func (r *tokenRepository) GetUserTokens(userId string) ([]listing.Token, error) {
+	rows, err := r.getUserTokensStmt.Query(userId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var result []listing.Token
+	for rows.Next() {
+		var t token
+		if err := rows.Scan(&t.id, &t.userId, &t.name, pq.Array(&t.scopes), &t.createdAt, &t.lastUsedAt); err != nil {
+			return nil, err
+		}
+		result = append(result, &t)
+	}
+	return result, rows.Err()
+}
+
+func (r *tokenRepository) TouchToken(id int, usedAt time.Time) error {
+	_, err := r.touchTokenStmt.Exec(usedAt.UTC(), id)
+	return err
+}
+
+func (r *tokenRepository) RemoveUserToken(userId string, id int) error {
+	_, err := r.removeUserTokenStmt.Exec(userId, id)
+	return err
+}
+
+type token struct {
+	id         int
+	userId     string
+	name       string
+	scopes     []string
+	createdAt  time.Time
+	lastUsedAt *time.Time
+}
+
+func (t *token) Id() int {
+	return t.id
+}
+
+func (t *token) UserId() string {
+	return t.userId
+}
+
+func (t *token) Name() string {
+	return t.name
+}
+
+func (t *token) Scopes() []string {
+	return t.scopes
+}
+
+func (t *token) CreatedAt() time.Time {
+	return t.createdAt
+}
+
+func (t *token) LastUsedAt() *time.Time {
+	return t.lastUsedAt
+}
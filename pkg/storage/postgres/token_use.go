@@ -0,0 +1,44 @@
+package postgres
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/themisir/myfeed/pkg/adding"
+)
+
+type usedTokenRepository struct {
+	c                 *Connection
+	addUsedTokenStmt  *sql.Stmt
+	removeExpiredStmt *sql.Stmt
+}
+
+const (
+	addUsedTokenQuery       = `INSERT INTO used_tokens (id, expires_at) VALUES ($1, $2) ON CONFLICT (id) DO NOTHING`
+	removeExpiredUsedTokens = `DELETE FROM used_tokens WHERE expires_at <= $1`
+)
+
+func newUsedTokenRepository(c *Connection) (r *usedTokenRepository, err error) {
+	r = &usedTokenRepository{c: c}
+	err = c.Batch().
+		Prepare(addUsedTokenQuery, &r.addUsedTokenStmt).
+		Prepare(removeExpiredUsedTokens, &r.removeExpiredStmt).
+		Exec()
+	return
+}
+
+func (r *usedTokenRepository) AddUsedToken(id string, expiresAt time.Time) error {
+	if _, err := r.removeExpiredStmt.Exec(time.Now().UTC()); err != nil {
+		return err
+	}
+	res, err := r.addUsedTokenStmt.Exec(id, expiresAt.UTC())
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return adding.ErrAlreadyExists
+	}
+	return nil
+}
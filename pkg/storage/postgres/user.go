@@ -13,12 +13,16 @@ type userRepository struct {
 	addUserStmt            *sql.Stmt
 	getUserByIdStmt        *sql.Stmt
 	findUserByUsernameStmt *sql.Stmt
+	verifyUserEmailStmt    *sql.Stmt
+	updateUserPasswordStmt *sql.Stmt
 }
 
 const (
-	addUserQuery            = `INSERT INTO users (id, email, username, normalized_username, password_hash) VALUES ($1, $2, $3, $4, $5)`
-	getUserByIdQuery        = `SELECT id, email, username, normalized_username, password_hash FROM users WHERE id = $1`
-	findUserByUsernameQuery = `SELECT id, email, username, normalized_username, password_hash FROM users WHERE normalized_username = $1`
+	addUserQuery            = `INSERT INTO users (id, email, username, normalized_username, password_hash, email_verified) VALUES ($1, $2, $3, $4, $5, $6)`
+	getUserByIdQuery        = `SELECT id, email, username, normalized_username, password_hash, email_verified FROM users WHERE id = $1`
+	findUserByUsernameQuery = `SELECT id, email, username, normalized_username, password_hash, email_verified FROM users WHERE normalized_username = $1`
+	verifyUserEmailQuery    = `UPDATE users SET email_verified = TRUE WHERE id = $1 AND email = $2`
+	updateUserPasswordQuery = `UPDATE users SET password_hash = $2 WHERE id = $1`
 )
 
 func newUserRepository(c *Connection) (r *userRepository, err error) {
@@ -27,6 +31,8 @@ func newUserRepository(c *Connection) (r *userRepository, err error) {
 		Prepare(addUserQuery, &r.addUserStmt).
 		Prepare(getUserByIdQuery, &r.getUserByIdStmt).
 		Prepare(findUserByUsernameQuery, &r.findUserByUsernameStmt).
+		Prepare(verifyUserEmailQuery, &r.verifyUserEmailStmt).
+		Prepare(updateUserPasswordQuery, &r.updateUserPasswordStmt).
 		Exec()
 	return
 }
@@ -34,7 +40,7 @@ func newUserRepository(c *Connection) (r *userRepository, err error) {
 func (r *userRepository) AddUser(data adding.UserData) (adding.User, error) {
 	id := uuid.New().String()
 	normalizedUsername := strings.ToUpper(data.Username)
-	_, err := r.addUserStmt.Exec(id, data.Email, data.Username, normalizedUsername, data.PasswordHash)
+	_, err := r.addUserStmt.Exec(id, data.Email, data.Username, normalizedUsername, data.PasswordHash, data.EmailVerified)
 	if err != nil {
 		return nil, err
 	}
@@ -44,12 +50,13 @@ func (r *userRepository) AddUser(data adding.UserData) (adding.User, error) {
 		username:           data.Username,
 		normalizedUsername: normalizedUsername,
 		passwordHash:       data.PasswordHash,
+		emailVerified:      data.EmailVerified,
 	}, nil
 }
 
 func (r *userRepository) GetUserById(id string) (listing.User, error) {
 	var u user
-	err := r.getUserByIdStmt.QueryRow(id).Scan(&u.id, &u.email, &u.username, &u.normalizedUsername, &u.passwordHash)
+	err := r.getUserByIdStmt.QueryRow(id).Scan(&u.id, &u.email, &u.username, &u.normalizedUsername, &u.passwordHash, &u.emailVerified)
 	if err != nil {
 		return nil, err
 	}
@@ -58,19 +65,30 @@ func (r *userRepository) GetUserById(id string) (listing.User, error) {
 
 func (r *userRepository) FindUserByUsername(username string) (listing.User, error) {
 	var u user
-	err := r.findUserByUsernameStmt.QueryRow(strings.ToUpper(username)).Scan(&u.id, &u.email, &u.username, &u.normalizedUsername, &u.passwordHash)
+	err := r.findUserByUsernameStmt.QueryRow(strings.ToUpper(username)).Scan(&u.id, &u.email, &u.username, &u.normalizedUsername, &u.passwordHash, &u.emailVerified)
 	if err != nil {
 		return nil, err
 	}
 	return &u, err
 }
 
+func (r *userRepository) VerifyUserEmail(id string, email string) error {
+	_, err := r.verifyUserEmailStmt.Exec(id, email)
+	return err
+}
+
+func (r *userRepository) UpdateUserPassword(id string, passwordHash string) error {
+	_, err := r.updateUserPasswordStmt.Exec(id, passwordHash)
+	return err
+}
+
 type user struct {
 	id                 string
 	email              string
 	username           string
 	normalizedUsername string
 	passwordHash       string
+	emailVerified      bool
 }
 
 func (u *user) Id() string {
@@ -88,3 +106,7 @@ func (u *user) PasswordHash() string {
 func (u *user) Username() string {
 	return u.username
 }
+
+func (u *user) EmailVerified() bool {
+	return u.emailVerified
+}
@@ -0,0 +1,103 @@
+package syndication
+
+import (
+	"encoding/xml"
+	"io"
+	"time"
+)
+
+type atomFeed struct {
+	XMLName   xml.Name    `xml:"http://www.w3.org/2005/Atom feed"`
+	Id        string      `xml:"id"`
+	Title     string      `xml:"title"`
+	Subtitle  string      `xml:"subtitle,omitempty"`
+	Updated   string      `xml:"updated"`
+	Links     []atomLink  `xml:"link"`
+	Generator string      `xml:"generator"`
+	Entries   []atomEntry `xml:"entry"`
+}
+
+type atomLink struct {
+	Href string `xml:"href,attr"`
+	Rel  string `xml:"rel,attr,omitempty"`
+	Type string `xml:"type,attr,omitempty"`
+}
+
+type atomEntry struct {
+	Id        string       `xml:"id"`
+	Title     string       `xml:"title"`
+	Updated   string       `xml:"updated"`
+	Published string       `xml:"published,omitempty"`
+	Links     []atomLink   `xml:"link"`
+	Content   *atomContent `xml:"content,omitempty"`
+	Source    *atomSource  `xml:"source,omitempty"`
+}
+
+type atomContent struct {
+	Type  string `xml:"type,attr"`
+	Value string `xml:",chardata"`
+}
+
+type atomSource struct {
+	Id    string     `xml:"id,omitempty"`
+	Title string     `xml:"title"`
+	Links []atomLink `xml:"link"`
+}
+
+func writeAtom(w io.Writer, feed *Feed) error {
+	updated := feed.LastModified()
+	if updated.IsZero() {
+		updated = time.Now()
+	}
+
+	doc := atomFeed{
+		Id:       feed.FeedUrl,
+		Title:    feed.Title,
+		Subtitle: feed.Description,
+		Updated:  updated.UTC().Format(time.RFC3339),
+		Links: []atomLink{
+			{Href: feed.Link, Rel: "alternate", Type: "text/html"},
+			{Href: feed.FeedUrl, Rel: "self", Type: atomContentType},
+		},
+		Generator: "myfeed",
+		Entries:   make([]atomEntry, len(feed.Items)),
+	}
+	if feed.NextUrl != "" {
+		doc.Links = append(doc.Links, atomLink{Href: feed.NextUrl, Rel: "next", Type: atomContentType})
+	}
+
+	for i := range feed.Items {
+		item := &feed.Items[i]
+		entry := atomEntry{
+			Id:      item.Id,
+			Title:   item.Title,
+			Updated: itemTime(item, updated).UTC().Format(time.RFC3339),
+			Links:   []atomLink{{Href: item.Link, Rel: "alternate"}},
+		}
+		if item.Published != nil {
+			entry.Published = item.Published.UTC().Format(time.RFC3339)
+		}
+		if item.Content != "" {
+			entry.Content = &atomContent{Type: "html", Value: item.Content}
+		}
+		if item.Source != nil {
+			source := &atomSource{Id: item.Source.FeedUrl, Title: item.Source.Title}
+			if item.Source.SiteUrl != "" {
+				source.Links = append(source.Links, atomLink{Href: item.Source.SiteUrl, Rel: "alternate"})
+			}
+			if item.Source.FeedUrl != "" {
+				source.Links = append(source.Links, atomLink{Href: item.Source.FeedUrl, Rel: "self"})
+			}
+			entry.Source = source
+		}
+		doc.Entries[i] = entry
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return formatError("atom", err)
+	}
+	if err := xml.NewEncoder(w).Encode(doc); err != nil {
+		return formatError("atom", err)
+	}
+	return nil
+}
@@ -0,0 +1,113 @@
+package syndication
+
+import (
+	"fmt"
+	"io"
+	"time"
+)
+
+// Feed is a format independent representation of a published feed
+type Feed struct {
+	Title       string
+	Description string
+
+	// Link is the url of the html page of the feed and FeedUrl is the url
+	// the feed is served from
+	Link    string
+	FeedUrl string
+
+	// NextUrl is the url of the page containing older items, if any
+	NextUrl string
+
+	// Updated is the time feed was last modified
+	Updated time.Time
+
+	Items []Item
+}
+
+type Item struct {
+	Id      string
+	Title   string
+	Link    string
+	Content string
+
+	Published *time.Time
+	Updated   *time.Time
+
+	// Source is the origin of the item
+	Source *Source
+}
+
+// Source attributes an item to the feed it was fetched from
+type Source struct {
+	Title   string
+	FeedUrl string
+	SiteUrl string
+}
+
+// Format serializes feeds into a specific document format
+type Format struct {
+	Name        string
+	ContentType string
+	Write       func(w io.Writer, feed *Feed) error
+}
+
+const (
+	rssContentType  = "application/rss+xml; charset=utf-8"
+	atomContentType = "application/atom+xml; charset=utf-8"
+	jsonContentType = "application/feed+json; charset=utf-8"
+)
+
+var (
+	RSS = &Format{
+		Name:        "rss",
+		ContentType: rssContentType,
+		Write:       writeRSS,
+	}
+	Atom = &Format{
+		Name:        "atom",
+		ContentType: atomContentType,
+		Write:       writeAtom,
+	}
+	JSON = &Format{
+		Name:        "json",
+		ContentType: jsonContentType,
+		Write:       writeJSON,
+	}
+)
+
+// Formats are the supported formats indexed by their names
+var Formats = map[string]*Format{
+	RSS.Name:  RSS,
+	Atom.Name: Atom,
+	JSON.Name: JSON,
+}
+
+// LastModified returns feed update time, falling back to the newest item
+// publish or update time
+func (f *Feed) LastModified() time.Time {
+	result := f.Updated
+	for _, item := range f.Items {
+		for _, t := range []*time.Time{item.Published, item.Updated} {
+			if t != nil && t.After(result) {
+				result = *t
+			}
+		}
+	}
+	return result
+}
+
+// itemTime returns update time of the item falling back to the publish time
+func itemTime(item *Item, fallback time.Time) time.Time {
+	if item.Updated != nil {
+		return *item.Updated
+	}
+	if item.Published != nil {
+		return *item.Published
+	}
+	return fallback
+}
+
+func formatError(format string, err error) error {
+	return fmt.Errorf("failed to write %s feed: %s", format, err)
+}
@@ -0,0 +1,87 @@
+package syndication
+
+import (
+	"encoding/json"
+	"io"
+	"time"
+)
+
+// JSON Feed 1.1 document, see https://www.jsonfeed.org/version/1.1/
+type jsonFeed struct {
+	Version     string     `json:"version"`
+	Title       string     `json:"title"`
+	HomePageUrl string     `json:"home_page_url,omitempty"`
+	FeedUrl     string     `json:"feed_url,omitempty"`
+	NextUrl     string     `json:"next_url,omitempty"`
+	Description string     `json:"description,omitempty"`
+	Items       []jsonItem `json:"items"`
+}
+
+type jsonItem struct {
+	Id            string       `json:"id"`
+	Url           string       `json:"url,omitempty"`
+	Title         string       `json:"title,omitempty"`
+	ContentHtml   string       `json:"content_html"`
+	DatePublished string       `json:"date_published,omitempty"`
+	DateModified  string       `json:"date_modified,omitempty"`
+	Authors       []jsonAuthor `json:"authors,omitempty"`
+
+	// Source is a custom extension attributing the item to its origin
+	Source *jsonSource `json:"_source,omitempty"`
+}
+
+type jsonAuthor struct {
+	Name string `json:"name"`
+	Url  string `json:"url,omitempty"`
+}
+
+type jsonSource struct {
+	Title   string `json:"title"`
+	FeedUrl string `json:"feed_url,omitempty"`
+	SiteUrl string `json:"home_page_url,omitempty"`
+}
+
+func writeJSON(w io.Writer, feed *Feed) error {
+	doc := jsonFeed{
+		Version:     "https://jsonfeed.org/version/1.1",
+		Title:       feed.Title,
+		HomePageUrl: feed.Link,
+		FeedUrl:     feed.FeedUrl,
+		NextUrl:     feed.NextUrl,
+		Description: feed.Description,
+		Items:       make([]jsonItem, len(feed.Items)),
+	}
+
+	for i := range feed.Items {
+		item := &feed.Items[i]
+		entry := jsonItem{
+			Id:          item.Id,
+			Url:         item.Link,
+			Title:       item.Title,
+			ContentHtml: item.Content,
+		}
+		if item.Published != nil {
+			entry.DatePublished = item.Published.UTC().Format(time.RFC3339)
+		}
+		if item.Updated != nil {
+			entry.DateModified = item.Updated.UTC().Format(time.RFC3339)
+		}
+		if item.Source != nil {
+			entry.Authors = []jsonAuthor{{Name: item.Source.Title, Url: item.Source.SiteUrl}}
+			entry.Source = &jsonSource{
+				Title:   item.Source.Title,
+				FeedUrl: item.Source.FeedUrl,
+				SiteUrl: item.Source.SiteUrl,
+			}
+		}
+		doc.Items[i] = entry
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(doc); err != nil {
+		return formatError("json", err)
+	}
+	return nil
+}
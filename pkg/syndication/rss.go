@@ -0,0 +1,105 @@
+package syndication
+
+import (
+	"encoding/xml"
+	"io"
+	"time"
+)
+
+type rssDocument struct {
+	XMLName   xml.Name   `xml:"rss"`
+	Version   string     `xml:"version,attr"`
+	AtomNS    string     `xml:"xmlns:atom,attr"`
+	ContentNS string     `xml:"xmlns:content,attr"`
+	Channel   rssChannel `xml:"channel"`
+}
+
+type rssChannel struct {
+	Title         string    `xml:"title"`
+	Link          string    `xml:"link"`
+	Description   string    `xml:"description"`
+	Links         []rssLink `xml:"atom:link"`
+	LastBuildDate string    `xml:"lastBuildDate,omitempty"`
+	Generator     string    `xml:"generator"`
+	Items         []rssItem `xml:"item"`
+}
+
+type rssLink struct {
+	Href string `xml:"href,attr"`
+	Rel  string `xml:"rel,attr"`
+	Type string `xml:"type,attr"`
+}
+
+type rssItem struct {
+	Title       string     `xml:"title"`
+	Link        string     `xml:"link"`
+	Guid        rssGuid    `xml:"guid"`
+	PubDate     string     `xml:"pubDate,omitempty"`
+	Description string     `xml:"description,omitempty"`
+	Content     *rssCDATA  `xml:"content:encoded,omitempty"`
+	Source      *rssSource `xml:"source,omitempty"`
+}
+
+type rssGuid struct {
+	Value       string `xml:",chardata"`
+	IsPermaLink bool   `xml:"isPermaLink,attr"`
+}
+
+type rssCDATA struct {
+	Value string `xml:",cdata"`
+}
+
+type rssSource struct {
+	Title string `xml:",chardata"`
+	Url   string `xml:"url,attr"`
+}
+
+func writeRSS(w io.Writer, feed *Feed) error {
+	doc := rssDocument{
+		Version:   "2.0",
+		AtomNS:    "http://www.w3.org/2005/Atom",
+		ContentNS: "http://purl.org/rss/1.0/modules/content/",
+		Channel: rssChannel{
+			Title:       feed.Title,
+			Link:        feed.Link,
+			Description: feed.Description,
+			Links:       []rssLink{{Href: feed.FeedUrl, Rel: "self", Type: rssContentType}},
+			Generator:   "myfeed",
+			Items:       make([]rssItem, len(feed.Items)),
+		},
+	}
+	if feed.NextUrl != "" {
+		doc.Channel.Links = append(doc.Channel.Links, rssLink{Href: feed.NextUrl, Rel: "next", Type: rssContentType})
+	}
+	if updated := feed.LastModified(); !updated.IsZero() {
+		doc.Channel.LastBuildDate = updated.UTC().Format(time.RFC1123Z)
+	}
+
+	for i := range feed.Items {
+		item := &feed.Items[i]
+		entry := rssItem{
+			Title: item.Title,
+			Link:  item.Link,
+			Guid:  rssGuid{Value: item.Id, IsPermaLink: item.Id == item.Link},
+		}
+		if item.Published != nil {
+			entry.PubDate = item.Published.UTC().Format(time.RFC1123Z)
+		}
+		if item.Content != "" {
+			entry.Description = item.Content
+			entry.Content = &rssCDATA{Value: item.Content}
+		}
+		if item.Source != nil {
+			entry.Source = &rssSource{Title: item.Source.Title, Url: item.Source.FeedUrl}
+		}
+		doc.Channel.Items[i] = entry
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return formatError("rss", err)
+	}
+	if err := xml.NewEncoder(w).Encode(doc); err != nil {
+		return formatError("rss", err)
+	}
+	return nil
+}
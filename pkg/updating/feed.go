@@ -3,9 +3,30 @@ package updating
 type Feed struct {
 	Name     string
 	IsPublic bool
+
+	// Retention limits of the feed, nil values mean instance defaults and
+	// zero values mean unlimited
+	RetentionDays  *int
+	RetentionPosts *int
+}
+
+type FeedSource struct {
+	SourceId int
+	// Title overrides the source title within the feed when not empty
+	Title string
+}
+
+type FeedRule struct {
+	Action  string
+	Field   string
+	Matcher string
+	Pattern string
 }
 
 type FeedRepository interface {
 	UpdateFeed(feedId int, data Feed) error
-	UpdateFeedSources(feedId int, sourceIds ...int) error
+	UpdateFeedSources(feedId int, sources ...FeedSource) error
+
+	// UpdateFeedRules replaces rules of the feed
+	UpdateFeedRules(feedId int, rules ...FeedRule) error
 }
@@ -12,4 +12,12 @@ type Post struct {
 
 type PostRepository interface {
 	UpdateSourcePost(sourceId int, postId int, data Post) error
+
+	// MarkPostRead sets read state of the post for the user, returns
+	// listing.ErrNotFound when the post isn't in a feed visible to the user
+	MarkPostRead(userId string, postId int, read bool) error
+
+	// MarkFeedRead marks posts of the feed published before the given time
+	// as read for the user, every post is marked when before is nil
+	MarkFeedRead(userId string, feedId int, before *time.Time) error
 }
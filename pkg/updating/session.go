@@ -0,0 +1,7 @@
+package updating
+
+import "time"
+
+type SessionRepository interface {
+	TouchSession(id string, seenAt time.Time, ip string) error
+}
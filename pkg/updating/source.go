@@ -1,9 +1,28 @@
 package updating
 
+import "time"
+
 type Source struct {
-	Title string
+	Title        string
+	Description  string
+	SiteUrl      string
+	ImageUrl     string
+	Diagnostics  string
+	ETag         string
+	LastModified string
+
+	// ItemCount is the number of items currently present in the source
+	ItemCount int
 }
 
 type SourceRepository interface {
 	UpdateSource(sourceId int, data Source) error
+	ScheduleSource(sourceId int, nextFetchAt time.Time) error
+
+	// RecordSourceSuccess resets failure count of the source
+	RecordSourceSuccess(sourceId int, status int, at time.Time) error
+
+	// RecordSourceFailure increments failure count of the source and
+	// returns the updated count
+	RecordSourceFailure(sourceId int, status int, message string) (int, error)
 }
@@ -0,0 +1,7 @@
+package updating
+
+import "time"
+
+type TokenRepository interface {
+	TouchToken(id int, usedAt time.Time) error
+}
@@ -0,0 +1,7 @@
+package updating
+
+type UserRepository interface {
+	// VerifyUserEmail marks email of the user verified unless it's changed
+	VerifyUserEmail(id string, email string) error
+	UpdateUserPassword(id string, passwordHash string) error
+}
@@ -0,0 +1,109 @@
+package web
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+// GET /account
+func (a *App) getAccountHandler(c echo.Context) error {
+	return a.renderAccount(c, echo.Map{})
+}
+
+// renderAccount renders account page of the current user with additional
+// template values
+func (a *App) renderAccount(c echo.Context, data echo.Map) error {
+	userId, err := GetUserId(c)
+	if err != nil {
+		c.Logger().Errorf("Failed to get user id: %s", err)
+		return echo.ErrInternalServerError
+	}
+	sessionId, err := GetSessionId(c)
+	if err != nil {
+		c.Logger().Errorf("Failed to get session id: %s", err)
+		return echo.ErrInternalServerError
+	}
+
+	sessions, err := a.sessions.GetUserSessions(userId, time.Now())
+	if err != nil {
+		c.Logger().Errorf("Failed to get sessions: %s", err)
+		return echo.ErrInternalServerError
+	}
+
+	identities, err := a.identities.GetUserIdentities(userId)
+	if err != nil {
+		c.Logger().Errorf("Failed to get identities: %s", err)
+		return echo.ErrInternalServerError
+	}
+
+	tokens, err := a.tokens.GetUserTokens(userId)
+	if err != nil {
+		c.Logger().Errorf("Failed to get tokens: %s", err)
+		return echo.ErrInternalServerError
+	}
+
+	// Current session is shown first
+	for i, session := range sessions {
+		if session.Id() == sessionId {
+			copy(sessions[1:i+1], sessions[:i])
+			sessions[0] = session
+			break
+		}
+	}
+
+	data["Sessions"] = sessions
+	data["SessionId"] = sessionId
+	data["Identities"] = identities
+	data["Tokens"] = tokens
+	data["Title"] = "Account"
+	return c.Render(http.StatusOK, "account.html", data)
+}
+
+// POST /account/sessions/:sessionId/revoke
+func (a *App) postSessionRevokeHandler(c echo.Context) error {
+	userId, err := GetUserId(c)
+	if err != nil {
+		c.Logger().Errorf("Failed to get user id: %s", err)
+		return echo.ErrInternalServerError
+	}
+	sessionId, err := GetSessionId(c)
+	if err != nil {
+		c.Logger().Errorf("Failed to get session id: %s", err)
+		return echo.ErrInternalServerError
+	}
+
+	revokedId := c.Param("sessionId")
+	if err := a.sessions.RemoveUserSession(userId, revokedId); err != nil {
+		c.Logger().Errorf("Failed to revoke session: %s", err)
+		return echo.ErrInternalServerError
+	}
+
+	// Revoking the current session logs the user out
+	if revokedId == sessionId {
+		return c.Redirect(http.StatusSeeOther, "/logout")
+	}
+	return c.Redirect(http.StatusSeeOther, "/account")
+}
+
+// POST /account/sessions/revoke-others
+func (a *App) postSessionsRevokeOthersHandler(c echo.Context) error {
+	userId, err := GetUserId(c)
+	if err != nil {
+		c.Logger().Errorf("Failed to get user id: %s", err)
+		return echo.ErrInternalServerError
+	}
+	sessionId, err := GetSessionId(c)
+	if err != nil {
+		c.Logger().Errorf("Failed to get session id: %s", err)
+		return echo.ErrInternalServerError
+	}
+
+	if err := a.sessions.RemoveOtherSessions(userId, sessionId); err != nil {
+		c.Logger().Errorf("Failed to revoke sessions: %s", err)
+		return echo.ErrInternalServerError
+	}
+
+	return c.Redirect(http.StatusSeeOther, "/account")
+}
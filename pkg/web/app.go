@@ -1,22 +1,33 @@
 package web
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"github.com/themisir/myfeed/pkg/log"
 	"io/fs"
+	"net"
 	"net/http"
 	"net/mail"
 	"os"
+	"os/signal"
+	"strings"
+	"sync"
+	"syscall"
 	"time"
 
 	"github.com/labstack/echo/v4"
 	"github.com/labstack/echo/v4/middleware"
 	"github.com/themisir/myfeed/pkg/adding"
 	"github.com/themisir/myfeed/pkg/auth"
+	"github.com/themisir/myfeed/pkg/filtering"
+	"github.com/themisir/myfeed/pkg/mailer"
 	"github.com/themisir/myfeed/pkg/models"
+	"github.com/themisir/myfeed/pkg/oidc"
+	"github.com/themisir/myfeed/pkg/removing"
 	"github.com/themisir/myfeed/pkg/sources"
 	"github.com/themisir/myfeed/pkg/storage/postgres"
+	"github.com/themisir/myfeed/pkg/syndication"
 	"github.com/themisir/myfeed/pkg/web/renderer"
 )
 
@@ -26,21 +37,81 @@ type AppConfig struct {
 	AssetsRoot   string
 	StaticFS     fs.FS
 	DataSource   string
+
+	// Bounds of the source refresh interval, defaults are used when zero
+	MinRefreshInterval time.Duration
+	MaxRefreshInterval time.Duration
+
+	// Number of concurrent source fetches in total and per host, defaults
+	// are used when zero
+	FetchWorkers         int
+	FetchHostConcurrency int
+
+	// Retention limits of feeds without custom limits, posts are kept
+	// forever when zero
+	RetentionDays  int
+	RetentionPosts int
+
+	// Keys used for signing session tokens, AuthActiveKey selects the key
+	// signing new tokens and the first key is used when it's empty. Random
+	// key is generated when no keys are configured.
+	AuthKeys      []auth.Key
+	AuthActiveKey string
+
+	// OpenID Connect providers users can sign in with
+	OIDCProviders []oidc.Config
+
+	// BaseUrl is the public url of the app used in links sent by email,
+	// emails containing links aren't sent when it's empty
+	BaseUrl string
+
+	// MailerUrl configures delivery of emails, see mailer.Open. Emails
+	// aren't sent when it's empty.
+	MailerUrl string
+	MailFrom  string
+
+	// TrustedProxies are ips or cidr ranges of the reverse proxies whose
+	// X-Forwarded-For header is trusted. Client ips are taken from the
+	// connection when it's empty.
+	TrustedProxies []string
 }
 
+// Time given to in-flight requests to complete on shutdown
+const shutdownTimeout = 30 * time.Second
+
 type App struct {
 	fs     fs.FS
 	config *AppConfig
 
-	sources models.SourceRepository
-	feeds   models.FeedRepository
-	posts   models.PostRepository
-	users   models.UserRepository
+	db *postgres.Connection
+
+	sources    models.SourceRepository
+	feeds      models.FeedRepository
+	posts      models.PostRepository
+	users      models.UserRepository
+	saved      models.SavedItemRepository
+	sessions   models.SessionRepository
+	identities models.IdentityRepository
+	tokens     models.TokenRepository
+	usedTokens models.UsedTokenRepository
+
+	// unfilteredPosts lists posts ignoring feed rules
+	unfilteredPosts models.PostRepository
 
 	logger   log.Logger
 	renderer *renderer.MetadataRenderer
 
+	// emails renders email templates without the layout
+	emails  echo.Renderer
+	mailer  mailer.Mailer
+	sending sync.WaitGroup
+
+	actions *auth.ActionTokens
+
 	sourceManager *sources.Manager
+
+	// providers are OpenID Connect providers indexed by their names
+	providers map[string]*oidc.Provider
 }
 
 func NewApp(config *AppConfig) *App {
@@ -60,8 +131,19 @@ func NewApp(config *AppConfig) *App {
 func (a *App) Run() {
 	e := echo.New()
 
+	// Client ips are recorded on sessions, so they aren't taken from headers
+	// set by untrusted clients
+	var err error
+	e.IPExtractor, err = ipExtractor(a.config.TrustedProxies)
+	initerr(err, "invalid trusted proxies: %s")
+
 	// Configure renderer
-	a.renderer = renderer.Metadata(renderer.Layout("layout.html", renderer.Template(a.fs, a.config.TemplateRoot)))
+	funcs := renderer.SafeFuncs(func(s string) string {
+		return sources.SanitizeHTML(s, nil)
+	})
+	templates := renderer.Template(a.fs, a.config.TemplateRoot, funcs)
+	a.renderer = renderer.Metadata(renderer.Layout("layout.html", templates))
+	a.emails = templates
 	e.Renderer = a.renderer
 
 	e.Pre(middleware.RemoveTrailingSlash())
@@ -75,20 +157,73 @@ func (a *App) Run() {
 	a.logger = e.Logger
 
 	a.initStorage()
+	a.initMailer()
 	a.initAuth(e)
 	a.initManager()
 	a.initRoutes(e)
 
-	if err := e.Start(a.config.Address); err != nil {
+	// Start server
+	serverErr := make(chan error, 1)
+	go func() {
+		serverErr <- e.Start(a.config.Address)
+	}()
+
+	// Wait for termination signal
+	quit := make(chan os.Signal, 1)
+	signal.Notify(quit, os.Interrupt, syscall.SIGTERM)
+
+	select {
+	case err := <-serverErr:
 		a.logger.Errorf("failed to start server: %s", err)
+	case sig := <-quit:
+		a.logger.Infof("received %s, shutting down", sig)
+	}
+
+	a.shutdown(e)
+}
+
+// shutdown stops accepting new requests, waits for in-flight ones, stops
+// source manager and closes database connection
+func (a *App) shutdown(e *echo.Echo) {
+	ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+
+	if err := e.Shutdown(ctx); err != nil {
+		a.logger.Errorf("failed to shut down server: %s", err)
+	}
+
+	a.sourceManager.Stop()
+
+	// Wait for emails being sent
+	sent := make(chan struct{})
+	go func() {
+		a.sending.Wait()
+		close(sent)
+	}()
+	select {
+	case <-sent:
+	case <-ctx.Done():
+		a.logger.Errorf("failed to send pending emails before shutdown")
+	}
+
+	if err := a.db.Close(); err != nil {
+		a.logger.Errorf("failed to close database connection: %s", err)
 	}
 }
 
 func (a *App) initAuth(e *echo.Echo) {
-	handler := auth.New(auth.CookieSchema([]byte("hello"), 30*24*time.Hour))
+	keys := a.authKeys()
+	handler := auth.New(auth.CompositeSchema(
+		auth.SessionSchema(auth.CookieSchema(keys, 30*24*time.Hour), a.sessions),
+		auth.BearerSchema(a.tokens),
+	))
 
 	e.Use(handler.Init)
 
+	e.GET("/.well-known/jwks.json", func(c echo.Context) error {
+		return c.JSON(http.StatusOK, echo.Map{"keys": keys.PublicKeys()})
+	})
+
 	e.GET("/login", func(c echo.Context) error {
 		return c.Render(http.StatusOK, "login.html", echo.Map{"Title": "Login"})
 	})
@@ -165,6 +300,10 @@ func (a *App) initAuth(e *echo.Echo) {
 			c.Logger().Errorf("Failed to create first feed for user '%s': %s", user.Id(), err)
 		}
 
+		if err := a.sendVerificationEmail(c, user); err != nil && err != errEmailDisabled {
+			c.Logger().Errorf("Failed to send verification email to user '%s': %s", user.Id(), err)
+		}
+
 		if err := handler.SignInWithoutPassword(c, user); err != nil {
 			c.Logger().Errorf("Failed to sign in with user '%s': %s", user.Id(), err)
 			return c.Redirect(http.StatusSeeOther, "/login")
@@ -173,6 +312,9 @@ func (a *App) initAuth(e *echo.Echo) {
 		return c.Redirect(http.StatusSeeOther, "/feeds")
 	})
 
+	a.initOIDC(e, handler)
+	a.initEmails(e, keys)
+
 	e.GET("/logout", func(c echo.Context) error {
 		handler.SignOut(c)
 		return c.Redirect(http.StatusSeeOther, "/")
@@ -201,41 +343,151 @@ func (a *App) initAuth(e *echo.Echo) {
 func (a *App) initStorage() {
 	db, err := postgres.Connect(a.config.DataSource)
 	initerr(err, "failed to connect to the database: %s")
+	a.db = db
 
 	a.feeds, err = db.Feeds()
 	initerr(err, "failed to create feed repository: %s")
 
-	a.posts, err = db.Posts()
+	a.unfilteredPosts, err = db.Posts()
 	initerr(err, "failed to create post repository: %s")
+	a.posts = filtering.NewPostRepository(a.unfilteredPosts, a.feeds)
 
 	a.sources, err = db.Sources()
 	initerr(err, "failed to create source repository: %s")
 
 	a.users, err = db.Users()
 	initerr(err, "failed to create user repository: %s")
+
+	a.saved, err = db.SavedItems()
+	initerr(err, "failed to create saved item repository: %s")
+
+	a.sessions, err = db.Sessions()
+	initerr(err, "failed to create session repository: %s")
+
+	a.identities, err = db.Identities()
+	initerr(err, "failed to create identity repository: %s")
+
+	a.tokens, err = db.Tokens()
+	initerr(err, "failed to create token repository: %s")
+
+	a.usedTokens, err = db.UsedTokens()
+	initerr(err, "failed to create used token repository: %s")
+}
+
+func (a *App) authKeys() *auth.KeySet {
+	if len(a.config.AuthKeys) == 0 {
+		a.logger.Warnf("no auth keys are configured, sessions will be invalidated on restart")
+		keys, err := auth.RandomKeySet()
+		initerr(err, "failed to generate auth key: %s")
+		return keys
+	}
+
+	keys, err := auth.NewKeySet(a.config.AuthActiveKey, a.config.AuthKeys...)
+	initerr(err, "failed to load auth keys: %s")
+	return keys
 }
 
 func (a *App) initManager() {
-	a.sourceManager = sources.NewManager(a.sources, a.posts, a.feeds, a.logger)
-	if err := a.sourceManager.Start(); err != nil {
+	a.sourceManager = sources.NewManager(a.sources, a.unfilteredPosts, a.feeds, a.logger)
+	if a.config.MinRefreshInterval > 0 {
+		a.sourceManager.Scheduler.MinInterval = a.config.MinRefreshInterval
+	}
+	if a.config.MaxRefreshInterval > 0 {
+		a.sourceManager.Scheduler.MaxInterval = a.config.MaxRefreshInterval
+	}
+	if a.config.FetchWorkers > 0 {
+		a.sourceManager.Workers = a.config.FetchWorkers
+	}
+	if a.config.FetchHostConcurrency > 0 {
+		a.sourceManager.HostConcurrency = a.config.FetchHostConcurrency
+	}
+	a.sourceManager.Retention = removing.Retention{
+		MaxAgeDays: a.config.RetentionDays,
+		MaxPosts:   a.config.RetentionPosts,
+	}
+	if err := a.sourceManager.Start(context.Background()); err != nil {
 		initerr(err, "failed to start source manager: %s")
 	}
 }
 
 func (a *App) initRoutes(e *echo.Echo) {
+	// Personal access tokens are limited by their scopes
+	writeScope := RequireScope(auth.ScopeFeedsWrite)
+	accountScope := RequireScope(auth.ScopeAccount)
+
 	e.GET("/", a.getIndexHandler)
 
 	e.GET("/feeds", a.getFeedsHandler, Authorize(true))
 
+	e.GET("/search", a.getSearchHandler)
+
 	e.GET("/feeds/:feedId", a.getFeedHandler)
 
 	e.GET("/feeds/create", a.getFeedsCreateHandler, Authorize(true))
-	e.POST("/feeds/create", a.postFeedsCreateHandler, Authorize(true))
+	e.POST("/feeds/create", a.postFeedsCreateHandler, Authorize(true), writeScope)
+
+	e.POST("/feeds/delete", a.postFeedsDeleteHandler, Authorize(true), writeScope)
 
-	e.POST("/feeds/delete", a.postFeedsDeleteHandler, Authorize(true))
+	e.GET("/feeds/opml", a.getFeedsOpmlHandler, Authorize(true))
+	e.GET("/feeds/import", a.getFeedsImportHandler, Authorize(true))
+	e.POST("/feeds/import", a.postFeedsImportHandler, Authorize(true), writeScope)
+	e.GET("/feeds/:feedId/opml", a.getFeedOpmlHandler)
 
 	e.GET("/feeds/:feedId/edit", a.getFeedsEditHandler, Authorize(true))
-	e.POST("/feeds/:feedId/edit", a.postFeedsEditHandler, Authorize(true))
+	e.POST("/feeds/:feedId/edit", a.postFeedsEditHandler, Authorize(true), writeScope)
+	e.POST("/feeds/:feedId/rules/preview", a.postFeedRulesPreviewHandler, Authorize(true))
+	e.POST("/feeds/:feedId/read", a.postFeedReadHandler, Authorize(true), writeScope)
+
+	e.POST("/posts/:postId/read", a.postPostReadHandler(true), Authorize(true), writeScope)
+	e.POST("/posts/:postId/unread", a.postPostReadHandler(false), Authorize(true), writeScope)
+	e.POST("/posts/:postId/save", a.postPostSaveHandler(true), Authorize(true), writeScope)
+	e.POST("/posts/:postId/unsave", a.postPostSaveHandler(false), Authorize(true), writeScope)
+
+	e.GET("/account", a.getAccountHandler, Authorize(true), accountScope)
+	e.POST("/account/sessions/:sessionId/revoke", a.postSessionRevokeHandler, Authorize(true), accountScope)
+	e.POST("/account/sessions/revoke-others", a.postSessionsRevokeOthersHandler, Authorize(true), accountScope)
+	e.POST("/account/identities/remove", a.postIdentityRemoveHandler, Authorize(true), accountScope)
+	e.POST("/account/tokens", a.postTokenCreateHandler, Authorize(true), accountScope)
+	e.POST("/account/tokens/:tokenId/revoke", a.postTokenRevokeHandler, Authorize(true), accountScope)
+
+	e.GET("/saved", a.getSavedHandler, Authorize(true))
+	e.GET("/saved.rss", a.getSavedExportHandler(syndication.RSS), Authorize(true))
+	e.GET("/saved.json", a.getSavedExportHandler(syndication.JSON), Authorize(true))
+	e.POST("/saved/:itemId/remove", a.postSavedRemoveHandler, Authorize(true), writeScope)
+}
+
+// ipExtractor returns extractor reading client ips from X-Forwarded-For
+// header set by the given proxies, ips of the direct peers are used when no
+// proxies are given
+func ipExtractor(proxies []string) (echo.IPExtractor, error) {
+	if len(proxies) == 0 {
+		return echo.ExtractIPDirect(), nil
+	}
+
+	options := []echo.TrustOption{
+		echo.TrustLoopback(false),
+		echo.TrustLinkLocal(false),
+		echo.TrustPrivateNet(false),
+	}
+	for _, proxy := range proxies {
+		if !strings.Contains(proxy, "/") {
+			ip := net.ParseIP(proxy)
+			if ip == nil {
+				return nil, fmt.Errorf("'%s' is not an ip or cidr range", proxy)
+			}
+			bits := 8 * net.IPv6len
+			if ip.To4() != nil {
+				bits = 8 * net.IPv4len
+			}
+			proxy = fmt.Sprintf("%s/%v", ip, bits)
+		}
+		_, ipRange, err := net.ParseCIDR(proxy)
+		if err != nil {
+			return nil, err
+		}
+		options = append(options, echo.TrustIPRange(ipRange))
+	}
+	return echo.ExtractIPFromXFFHeader(options...), nil
 }
 
 func initerr(err error, format string) {
@@ -0,0 +1,53 @@
+package web
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+)
+
+func TestIPExtractor(t *testing.T) {
+	tests := []struct {
+		name       string
+		proxies    []string
+		remoteAddr string
+		forwarded  string
+		want       string
+	}{
+		{"direct", nil, "203.0.113.5:1234", "", "203.0.113.5"},
+		{"spoofed without proxies", nil, "203.0.113.5:1234", "198.51.100.7", "203.0.113.5"},
+		{"spoofed from private network", []string{"192.168.1.10"}, "10.0.0.5:1234", "198.51.100.7", "10.0.0.5"},
+		{"trusted proxy", []string{"192.168.1.10"}, "192.168.1.10:1234", "198.51.100.7", "198.51.100.7"},
+		{"trusted range", []string{"10.0.0.0/8", "2001:db8::1"}, "10.1.2.3:1234", "198.51.100.7", "198.51.100.7"},
+		{"trusted ipv6 proxy", []string{"2001:db8::1"}, "[2001:db8::1]:1234", "198.51.100.7", "198.51.100.7"},
+		// Entries prepended by the client are skipped
+		{"spoofed through proxy", []string{"192.168.1.10"}, "192.168.1.10:1234", "1.1.1.1, 198.51.100.7", "198.51.100.7"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			extract, err := ipExtractor(test.proxies)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.RemoteAddr = test.remoteAddr
+			if test.forwarded != "" {
+				req.Header.Set(echo.HeaderXForwardedFor, test.forwarded)
+			}
+			if got := extract(req); got != test.want {
+				t.Errorf("got %s, want %s", got, test.want)
+			}
+		})
+	}
+}
+
+func TestIPExtractorRejectsInvalidProxies(t *testing.T) {
+	for _, proxy := range []string{"proxy.local", "10.0.0.0/33", ""} {
+		if _, err := ipExtractor([]string{proxy}); err == nil {
+			t.Errorf("%q is accepted", proxy)
+		}
+	}
+}
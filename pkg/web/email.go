@@ -0,0 +1,257 @@
+package web
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/themisir/myfeed/pkg/auth"
+	"github.com/themisir/myfeed/pkg/listing"
+	"github.com/themisir/myfeed/pkg/mailer"
+)
+
+// Validity of the links sent by email
+const (
+	verifyEmailLifetime   = 24 * time.Hour
+	resetPasswordLifetime = time.Hour
+)
+
+// Sender used when MailFrom is not configured
+const defaultMailFrom = "myfeed <noreply@localhost>"
+
+var errEmailDisabled = errors.New("emails are disabled since mailer or base url is not configured")
+
+func (a *App) initMailer() {
+	switch a.config.MailerUrl {
+	case "":
+		a.logger.Warnf("no mailer is configured, verification and password reset emails are disabled")
+	case "log":
+		// Logged emails contain links with live tokens
+		a.logger.Warnf("emails are written to the log including their links, use it only for development")
+		fallthrough
+	default:
+		var err error
+		a.mailer, err = mailer.Open(a.config.MailerUrl, a.logger)
+		initerr(err, "failed to create mailer: %s")
+	}
+
+	// Links aren't built from the host header of the requests, since it's
+	// controlled by the client
+	if a.config.BaseUrl == "" {
+		a.logger.Warnf("no base url is configured, verification and password reset emails are disabled")
+	} else if u, err := url.Parse(a.config.BaseUrl); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		initerr(fmt.Errorf("'%s' is not an absolute http(s) url", a.config.BaseUrl), "invalid base url: %s")
+	}
+	a.renderer.Set("EmailEnabled", a.emailEnabled())
+}
+
+// emailEnabled reports whether emails containing links can be sent
+func (a *App) emailEnabled() bool {
+	return a.mailer != nil && a.config.BaseUrl != ""
+}
+
+func (a *App) initEmails(e *echo.Echo, keys *auth.KeySet) {
+	a.actions = auth.NewActionTokens(keys, a.usedTokens)
+
+	e.GET("/verify-email", a.getVerifyEmailHandler)
+	e.POST("/account/verify-email", a.postVerifyEmailHandler, Authorize(true), RequireScope(auth.ScopeAccount))
+
+	e.GET("/forgot-password", func(c echo.Context) error {
+		return c.Render(http.StatusOK, "forgot-password.html", echo.Map{"Title": "Forgot password"})
+	})
+	e.POST("/forgot-password", a.postForgotPasswordHandler)
+	e.GET("/reset-password", a.getResetPasswordHandler)
+	e.POST("/reset-password", a.postResetPasswordHandler)
+}
+
+// publicUrl returns absolute url of the path for links sent by email
+func (a *App) publicUrl(path string) string {
+	return strings.TrimSuffix(a.config.BaseUrl, "/") + path
+}
+
+// sendEmail renders email template and sends it in the background, so slow
+// mail servers don't delay responses
+func (a *App) sendEmail(c echo.Context, to string, subject string, name string, data echo.Map) error {
+	body := new(bytes.Buffer)
+	if err := a.emails.Render(body, name, data, c); err != nil {
+		return err
+	}
+
+	from := a.config.MailFrom
+	if from == "" {
+		from = defaultMailFrom
+	}
+	msg := &mailer.Message{From: from, To: to, Subject: subject, HTML: body.String()}
+
+	a.sending.Add(1)
+	go func() {
+		defer a.sending.Done()
+		if err := a.mailer.Send(msg); err != nil {
+			a.logger.Errorf("failed to send '%s' email: %s", subject, err)
+		}
+	}()
+	return nil
+}
+
+func (a *App) sendVerificationEmail(c echo.Context, user listing.User) error {
+	if !a.emailEnabled() {
+		return errEmailDisabled
+	}
+
+	token, err := a.actions.Issue(auth.PurposeVerifyEmail, user, verifyEmailLifetime)
+	if err != nil {
+		return err
+	}
+	return a.sendEmail(c, user.Email(), "Verify your email", "emails/verify-email.html", echo.Map{
+		"Username": user.Username(),
+		"Url":      a.publicUrl("/verify-email?token=" + token),
+	})
+}
+
+// GET /verify-email
+func (a *App) getVerifyEmailHandler(c echo.Context) error {
+	user, err := a.actions.Redeem(auth.PurposeVerifyEmail, c.QueryParam("token"), a.users)
+	if err != nil {
+		if err != auth.ErrInvalidToken {
+			c.Logger().Errorf("Failed to redeem token: %s", err)
+			return echo.ErrInternalServerError
+		}
+		return c.Render(http.StatusOK, "verify-email.html", echo.Map{
+			"Error": "The link is invalid, expired or already used",
+			"Title": "Verify email",
+		})
+	}
+
+	if err := a.users.VerifyUserEmail(user.Id(), user.Email()); err != nil {
+		c.Logger().Errorf("Failed to verify email of user '%s': %s", user.Id(), err)
+		return echo.ErrInternalServerError
+	}
+
+	return c.Render(http.StatusOK, "verify-email.html", echo.Map{
+		"Email": user.Email(),
+		"Title": "Verify email",
+	})
+}
+
+// POST /account/verify-email
+// Sends verification email again
+func (a *App) postVerifyEmailHandler(c echo.Context) error {
+	userId, err := GetUserId(c)
+	if err != nil {
+		c.Logger().Errorf("Failed to get user id: %s", err)
+		return echo.ErrInternalServerError
+	}
+
+	user, err := a.users.GetUserById(userId)
+	if err != nil {
+		c.Logger().Errorf("Failed to get user '%s': %s", userId, err)
+		return echo.ErrInternalServerError
+	}
+
+	if !a.emailEnabled() {
+		return echo.NewHTTPError(http.StatusServiceUnavailable, "Sending emails is not configured")
+	}
+
+	if !user.EmailVerified() {
+		if err := a.sendVerificationEmail(c, user); err != nil {
+			c.Logger().Errorf("Failed to send verification email: %s", err)
+			return echo.ErrInternalServerError
+		}
+	}
+
+	return a.renderAccount(c, echo.Map{"VerificationSent": true})
+}
+
+// POST /forgot-password
+// Sends password reset link to the verified email of the user. Response
+// doesn't tell whether the user exists.
+func (a *App) postForgotPasswordHandler(c echo.Context) error {
+	if !a.emailEnabled() {
+		return c.Render(http.StatusOK, "forgot-password.html", echo.Map{
+			"Error": "Password reset by email is not available",
+			"Title": "Forgot password",
+		})
+	}
+
+	username := c.FormValue("username")
+
+	user, _ := a.users.FindUserByUsername(username)
+	if user != nil && user.EmailVerified() {
+		token, err := a.actions.Issue(auth.PurposeResetPassword, user, resetPasswordLifetime)
+		if err == nil {
+			err = a.sendEmail(c, user.Email(), "Reset your password", "emails/reset-password.html", echo.Map{
+				"Username": user.Username(),
+				"Url":      a.publicUrl("/reset-password?token=" + token),
+			})
+		}
+		if err != nil {
+			c.Logger().Errorf("Failed to send password reset email to user '%s': %s", user.Id(), err)
+		}
+	}
+
+	return c.Render(http.StatusOK, "forgot-password.html", echo.Map{
+		"Sent":  true,
+		"Title": "Forgot password",
+	})
+}
+
+// GET /reset-password
+// Token is redeemed on submit, so link scanners of mail servers don't use it
+func (a *App) getResetPasswordHandler(c echo.Context) error {
+	return c.Render(http.StatusOK, "reset-password.html", echo.Map{
+		"Token": c.QueryParam("token"),
+		"Title": "Reset password",
+	})
+}
+
+// POST /reset-password
+func (a *App) postResetPasswordHandler(c echo.Context) error {
+	token := c.FormValue("token")
+	password := c.FormValue("password")
+
+	if len(password) < 6 {
+		return c.Render(http.StatusOK, "reset-password.html", echo.Map{
+			"Error": "Password must be at least 6 characters long",
+			"Token": token,
+			"Title": "Reset password",
+		})
+	}
+
+	user, err := a.actions.Redeem(auth.PurposeResetPassword, token, a.users)
+	if err != nil {
+		if err != auth.ErrInvalidToken {
+			c.Logger().Errorf("Failed to redeem token: %s", err)
+			return echo.ErrInternalServerError
+		}
+		return c.Render(http.StatusOK, "reset-password.html", echo.Map{
+			"Error": "The link is invalid, expired or already used, please request a new one",
+			"Title": "Reset password",
+		})
+	}
+
+	handler, err := auth.GetHandler(c)
+	if err != nil {
+		c.Logger().Errorf("Failed to get auth handler: %s", err)
+		return echo.ErrInternalServerError
+	}
+
+	if err := a.users.UpdateUserPassword(user.Id(), handler.HashPassword(password)); err != nil {
+		c.Logger().Errorf("Failed to update password of user '%s': %s", user.Id(), err)
+		return echo.ErrInternalServerError
+	}
+
+	// Sign out everywhere in case the password is compromised
+	if err := a.sessions.RemoveOtherSessions(user.Id(), ""); err != nil {
+		c.Logger().Errorf("Failed to revoke sessions of user '%s': %s", user.Id(), err)
+	}
+
+	return c.Render(http.StatusOK, "login.html", echo.Map{
+		"Message": "Your password is changed, sign in with the new password",
+		"Title":   "Login",
+	})
+}
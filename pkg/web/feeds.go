@@ -1,16 +1,26 @@
 package web
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"net/http"
 	"strconv"
+	"time"
 
 	"github.com/labstack/echo/v4"
 	"github.com/themisir/myfeed/pkg/adding"
+	"github.com/themisir/myfeed/pkg/filtering"
 	"github.com/themisir/myfeed/pkg/listing"
+	"github.com/themisir/myfeed/pkg/removing"
+	"github.com/themisir/myfeed/pkg/sources"
 	"github.com/themisir/myfeed/pkg/updating"
 )
 
+// Maximum duration of discovering feeds on every url submitted at once, urls
+// left undiscovered are kept as is
+const sourceDiscoveryTimeout = 15 * time.Second
+
 // GET /
 func (a *App) getIndexHandler(c echo.Context) error {
 	var feeds []listing.Feed
@@ -20,7 +30,8 @@ func (a *App) getIndexHandler(c echo.Context) error {
 	}
 
 	return c.Render(http.StatusOK, "index.html", echo.Map{
-		"Feeds": feeds,
+		"Feeds":  feeds,
+		"Unread": a.unreadCounts(c),
 	})
 }
 
@@ -39,8 +50,9 @@ func (a *App) getFeedsHandler(c echo.Context) error {
 	}
 
 	return c.Render(http.StatusOK, "feeds/list.html", echo.Map{
-		"Feeds": feeds,
-		"Title": "Your feeds",
+		"Feeds":  feeds,
+		"Unread": a.unreadCounts(c),
+		"Title":  "Your feeds",
 	})
 }
 
@@ -101,13 +113,58 @@ func (a *App) getFeedsEditHandler(c echo.Context) error {
 		return echo.ErrInternalServerError
 	}
 
+	entries := make([]sourceEntry, len(sources))
+	for i, source := range sources {
+		entries[i] = sourceEntry{
+			Url:      source.Url(),
+			Title:    source.CustomTitle(),
+			Source:   source,
+			Disabled: a.sourceManager.Disabled(source),
+		}
+	}
+
+	// Get feed rules
+	rules, err := a.feeds.GetFeedRules(feedId)
+	if err != nil {
+		c.Logger().Errorf("Failed to list feed '%v' rules: %s", feedId, err)
+		return echo.ErrInternalServerError
+	}
+
+	return a.renderFeedEditor(c, feed, entries, filtering.FromFeedRules(rules), "")
+}
+
+// renderFeedEditor renders feed editor with the given entries and an optional
+// error message
+func (a *App) renderFeedEditor(c echo.Context, feed listing.Feed, entries []sourceEntry, rules []filtering.Rule, message string) error {
 	return c.Render(http.StatusOK, "feeds/edit.html", echo.Map{
-		"Feed":    feed,
-		"Sources": sources,
-		"Title":   "Edit feed",
+		"Feed":      feed,
+		"Sources":   entries,
+		"Rules":     ruleRows(rules),
+		"NewRule":   newRuleRow(filtering.Rule{}),
+		"Retention": a.retentionForm(feed),
+		"Title":     "Edit feed",
+		"Error":     message,
 	})
 }
 
+// sourceEntry is a single source row of the feed editor
+type sourceEntry struct {
+	Url string
+	// Title overrides source title within the feed
+	Title string
+
+	// Source is the stored source, nil for new entries
+	Source   listing.Source
+	Disabled bool
+
+	// Candidates are feeds discovered on the url, set when user has to
+	// pick one of them
+	Candidates []sources.Candidate
+
+	// Error tells why the url can't be added
+	Error string
+}
+
 // POST /feeds/delete
 func (a *App) postFeedsDeleteHandler(c echo.Context) error {
 	userId, err := GetUserId(c)
@@ -140,10 +197,64 @@ func (a *App) postFeedsDeleteHandler(c echo.Context) error {
 	return c.Redirect(http.StatusSeeOther, "/feeds")
 }
 
+// retentionForm is the retention section of the feed editor
+type retentionForm struct {
+	// Mode is one of "default", "forever" or "custom"
+	Mode  string
+	Days  int
+	Posts int
+
+	// Defaults are the instance wide limits
+	Defaults removing.Retention
+}
+
+func (a *App) retentionForm(feed listing.Feed) retentionForm {
+	form := retentionForm{Mode: "default", Defaults: a.sourceManager.Retention}
+	days, posts := feed.RetentionDays(), feed.RetentionPosts()
+	if days == nil && posts == nil {
+		return form
+	}
+
+	if days != nil {
+		form.Days = *days
+	}
+	if posts != nil {
+		form.Posts = *posts
+	}
+	if form.Days == 0 && form.Posts == 0 {
+		form.Mode = "forever"
+	} else {
+		form.Mode = "custom"
+	}
+	return form
+}
+
 type postFeedsEditDto struct {
 	Name    string   `form:"name"`
 	Sources []string `form:"sources"`
+	Titles  []string `form:"titles"`
 	Privacy string   `form:"privacy"`
+	Rules   rulesForm
+
+	Retention      string `form:"retention"`
+	RetentionDays  int    `form:"retention_days"`
+	RetentionPosts int    `form:"retention_posts"`
+}
+
+// retention returns retention limits of the feed, nil limits mean instance
+// defaults and zero limits mean unlimited
+func (d *postFeedsEditDto) retention() (days *int, posts *int) {
+	switch d.Retention {
+	case "forever":
+		return new(int), new(int)
+	case "custom":
+		if d.RetentionDays < 0 || d.RetentionPosts < 0 {
+			return new(int), new(int)
+		}
+		return &d.RetentionDays, &d.RetentionPosts
+	default:
+		return nil, nil
+	}
 }
 
 // POST /feeds/:feedId/edit
@@ -176,17 +287,68 @@ func (a *App) postFeedsEditHandler(c echo.Context) error {
 		return echo.ErrBadRequest
 	}
 
+	// Validate rules
+	rules := body.Rules.rules()
+	if _, err := filtering.Compile(rules); err != nil {
+		entries := make([]sourceEntry, len(body.Sources))
+		for i, url := range body.Sources {
+			entries[i] = sourceEntry{Url: url}
+			if i < len(body.Titles) {
+				entries[i].Title = body.Titles[i]
+			}
+		}
+		return a.renderFeedEditor(c, feed, entries, rules, fmt.Sprintf("Invalid %s", err))
+	}
+
 	// Update feed details
+	retentionDays, retentionPosts := body.retention()
 	if err := a.feeds.UpdateFeed(feedId, updating.Feed{
-		Name:     body.Name,
-		IsPublic: body.Privacy == "public",
+		Name:           body.Name,
+		IsPublic:       body.Privacy == "public",
+		RetentionDays:  retentionDays,
+		RetentionPosts: retentionPosts,
 	}); err != nil {
 		c.Logger().Errorf("Failed to update feed '%v': %s", feedId, err)
 		return echo.ErrInternalServerError
 	}
 
+	// Update feed rules
+	feedRules := make([]updating.FeedRule, len(rules))
+	for i, rule := range rules {
+		feedRules[i] = updating.FeedRule{
+			Action:  rule.Action,
+			Field:   rule.Field,
+			Matcher: rule.Matcher,
+			Pattern: rule.Pattern,
+		}
+	}
+	if err := a.feeds.UpdateFeedRules(feedId, feedRules...); err != nil {
+		c.Logger().Errorf("Failed to update feed '%v' rules: %s", feedId, err)
+		return echo.ErrInternalServerError
+	}
+
+	// Replace web page urls with discovered feeds
+	entries, message := a.discoverSources(c, body.Sources, body.Titles)
+	if message != "" {
+		// Let user pick between discovered feeds or fix rejected urls
+		feed, err = a.feeds.GetFeed(feedId)
+		if err != nil {
+			return echo.ErrNotFound
+		}
+
+		return a.renderFeedEditor(c, feed, entries, rules, message)
+	}
+
+	feedSources := make([]sources.FeedSource, len(entries))
+	for i, entry := range entries {
+		feedSources[i] = sources.FeedSource{
+			Url:   entry.Url,
+			Title: entry.Title,
+		}
+	}
+
 	// Update feed sources
-	if err := a.sourceManager.UpdateFeedSources(feedId, body.Sources...); err != nil {
+	if err := a.sourceManager.UpdateFeedSources(feedId, feedSources...); err != nil {
 		c.Logger().Errorf("Failed to update feed sources '%v': %s", feedId, err)
 		return echo.ErrInternalServerError
 	}
@@ -195,9 +357,11 @@ func (a *App) postFeedsEditHandler(c echo.Context) error {
 }
 
 // GET /feeds/:feedId
+// GET /feeds/:feedId.rss, /feeds/:feedId.atom, /feeds/:feedId.json
 func (a *App) getFeedHandler(c echo.Context) error {
-	// Parse feed id
-	feedId, err := strconv.Atoi(c.Param("feedId"))
+	// Parse feed id and format, echo can't route on the param suffix
+	param, format := splitFormat(c.Param("feedId"))
+	feedId, err := strconv.Atoi(param)
 	if err != nil {
 		return echo.ErrNotFound
 	}
@@ -209,27 +373,107 @@ func (a *App) getFeedHandler(c echo.Context) error {
 	}
 
 	// Check access
-	if !feed.IsPublic() {
-		userId, err := GetUserId(c)
-		if err != nil || userId != feed.UserId() {
-			return echo.ErrForbidden
-		}
+	userId, _ := GetUserId(c)
+	if !feed.IsPublic() && userId != feed.UserId() {
+		return echo.ErrForbidden
 	}
 
 	// Get posts
-	posts, err := a.posts.GetFeedPosts(feedId)
+	limit := postsPerPage
+	if format != nil {
+		limit = syndicationLimit
+	}
+	page, err := parsePage(c, limit)
+	if err != nil {
+		return echo.ErrBadRequest
+	}
+	// Read state is tracked for the logged in users only, syndication output
+	// is the same for everyone
+	if format == nil && userId != "" {
+		page.UserId = userId
+		page.Unread = c.QueryParam("unread") != ""
+	}
+	posts, scanned, err := a.posts.GetFeedPosts(feedId, page)
 	if err != nil {
 		c.Logger().Errorf("Failed to get feed '%v' posts: %s", feedId, err)
 		return echo.ErrInternalServerError
 	}
 
+	cursors := make([]listing.Cursor, len(posts))
+	for i, post := range posts {
+		cursors[i] = post.Cursor()
+	}
+	start, end, nav := pageRange(page, cursors, limit, scanned)
+	posts = posts[start:end]
+
+	if format != nil {
+		// Private feeds are exported to their owners only
+		if !feed.IsPublic() {
+			c.Response().Header().Set("Cache-Control", "private")
+		}
+		return writeSyndication(c, format, feedDocument(c, feed, posts, nav))
+	}
+
 	return c.Render(http.StatusOK, "feeds/single.html", echo.Map{
-		"Feed":  feed,
-		"Posts": posts,
-		"Title": feed.Name(),
+		"Feed":       feed,
+		"Posts":      posts,
+		"Page":       nav,
+		"Unread":     page.Unread,
+		"Title":      feed.Name(),
+		"Alternates": feedAlternates(feed),
 	})
 }
 
+// discoverSources replaces urls of web pages with feeds found on them. Urls
+// with multiple feeds found are returned with their candidates and urls
+// pointing to private network addresses are rejected, in both cases message
+// asking user to review the entries is returned.
+func (a *App) discoverSources(c echo.Context, urls []string, titles []string) (entries []sourceEntry, message string) {
+	entries = make([]sourceEntry, len(urls))
+
+	ctx, cancel := context.WithTimeout(c.Request().Context(), sourceDiscoveryTimeout)
+	defer cancel()
+
+	for i, url := range urls {
+		entries[i] = sourceEntry{Url: url}
+		if i < len(titles) {
+			entries[i].Title = titles[i]
+		}
+
+		// Skip existing sources
+		if source, _ := a.sources.FindSourceByUrl(url); source != nil {
+			entries[i].Source = source
+			entries[i].Disabled = a.sourceManager.Disabled(source)
+			continue
+		}
+
+		candidates, err := a.sourceManager.Discover(ctx, url)
+		if errors.Is(err, sources.ErrPrivateAddress) {
+			entries[i].Error = "Url points to a private network address"
+			message = "Some of the urls can't be added, please remove them"
+			continue
+		}
+		if err != nil {
+			c.Logger().Warnf("Failed to discover feeds on '%s': %s", url, err)
+			continue
+		}
+
+		switch len(candidates) {
+		case 0:
+			// Keep url as is
+		case 1:
+			entries[i].Url = candidates[0].Url
+		default:
+			entries[i].Candidates = candidates
+			if message == "" {
+				message = "Multiple feeds are found on some of the websites, please pick one of them"
+			}
+		}
+	}
+
+	return
+}
+
 func (a *App) createFirstFeed(user listing.User) error {
 	feedName := fmt.Sprintf("%s's personal feed", user.Username())
 	_, err := a.feeds.AddFeed(adding.FeedData{
@@ -10,6 +10,29 @@ func GetUserId(c echo.Context) (string, error) {
 	return auth.GetUserId(c)
 }
 
+func GetSessionId(c echo.Context) (string, error) {
+	return auth.GetSessionId(c)
+}
+
+// RequireScope rejects requests authorized without the given scope
+func RequireScope(scope string) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			handler, err := auth.GetHandler(c)
+			if err != nil {
+				c.Logger().Errorf("Failed to get auth handler: %s", err)
+				return echo.ErrInternalServerError
+			}
+
+			if !handler.HasScope(c, scope) {
+				return echo.ErrForbidden
+			}
+
+			return next(c)
+		}
+	}
+}
+
 func Authorize(redirectOnFailure bool) echo.MiddlewareFunc {
 	return func(next echo.HandlerFunc) echo.HandlerFunc {
 		return func(c echo.Context) error {
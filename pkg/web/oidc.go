@@ -0,0 +1,300 @@
+package web
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/themisir/myfeed/pkg/adding"
+	"github.com/themisir/myfeed/pkg/auth"
+	"github.com/themisir/myfeed/pkg/listing"
+	"github.com/themisir/myfeed/pkg/oidc"
+)
+
+// Name of the cookie keeping authorization request until the callback
+const oidcCookieName = "oidc"
+
+// Time given to the user for completing sign in on the provider
+const oidcCookieLifetime = 10 * time.Minute
+
+// Characters not allowed in the provisioned usernames
+var usernameReplacer = regexp.MustCompile(`[^A-Za-z0-9_.-]+`)
+
+// oidcRequest is the authorization request stored in the cookie
+type oidcRequest struct {
+	Provider string `json:"p"`
+	State    string `json:"s"`
+	Nonce    string `json:"n"`
+	Verifier string `json:"v"`
+}
+
+func (a *App) initOIDC(e *echo.Echo, handler *auth.Handler) {
+	a.providers = make(map[string]*oidc.Provider, len(a.config.OIDCProviders))
+	providers := make([]*oidc.Provider, len(a.config.OIDCProviders))
+	for i, config := range a.config.OIDCProviders {
+		providers[i] = oidc.NewProvider(config)
+		a.providers[config.Name] = providers[i]
+	}
+	a.renderer.Set("Providers", providers)
+
+	e.GET("/login/oidc/:provider", func(c echo.Context) error {
+		return a.getOIDCLoginHandler(c, handler)
+	})
+	e.GET("/login/oidc/:provider/callback", func(c echo.Context) error {
+		return a.getOIDCCallbackHandler(c, handler)
+	})
+}
+
+func oidcRedirectUrl(c echo.Context, provider *oidc.Provider) string {
+	return absoluteUrl(c, fmt.Sprintf("/login/oidc/%s/callback", provider.Name))
+}
+
+func oidcCookie(c echo.Context, value string, lifetime time.Duration) *http.Cookie {
+	return &http.Cookie{
+		Name:     oidcCookieName,
+		Value:    value,
+		Path:     "/login/oidc",
+		Expires:  time.Now().Add(lifetime),
+		Secure:   c.Scheme() == "https",
+		HttpOnly: true,
+		// Cookie has to be sent on redirect from the provider
+		SameSite: http.SameSiteLaxMode,
+	}
+}
+
+// linkingUserId returns id of the user identities are linked to. Linking
+// requires managing the account, so personal access tokens can't be used to
+// link identities and sign in with them.
+func linkingUserId(c echo.Context, handler *auth.Handler) string {
+	if !handler.HasScope(c, auth.ScopeAccount) {
+		return ""
+	}
+	return handler.GetUserId(c)
+}
+
+// GET /login/oidc/:provider
+// Signs in using the provider, identity is linked to the current user when
+// user is already logged in
+func (a *App) getOIDCLoginHandler(c echo.Context, handler *auth.Handler) error {
+	provider, ok := a.providers[c.Param("provider")]
+	if !ok {
+		return echo.ErrNotFound
+	}
+
+	if handler.GetUserId(c) != "" && !handler.HasScope(c, auth.ScopeAccount) {
+		return echo.ErrForbidden
+	}
+
+	authorization, err := provider.Authorize(c.Request().Context(), oidcRedirectUrl(c, provider))
+	if err != nil {
+		c.Logger().Errorf("Failed to start sign in with '%s': %s", provider.Name, err)
+		return a.renderLoginError(c, fmt.Sprintf("%s is not available at the moment, please try again later", provider.Title))
+	}
+
+	value, _ := json.Marshal(oidcRequest{
+		Provider: provider.Name,
+		State:    authorization.State,
+		Nonce:    authorization.Nonce,
+		Verifier: authorization.Verifier,
+	})
+	c.SetCookie(oidcCookie(c, base64.RawURLEncoding.EncodeToString(value), oidcCookieLifetime))
+
+	return c.Redirect(http.StatusSeeOther, authorization.Url)
+}
+
+// GET /login/oidc/:provider/callback
+func (a *App) getOIDCCallbackHandler(c echo.Context, handler *auth.Handler) error {
+	provider, ok := a.providers[c.Param("provider")]
+	if !ok {
+		return echo.ErrNotFound
+	}
+
+	// Authorization request can be used only once
+	request, err := readOIDCRequest(c)
+	c.SetCookie(oidcCookie(c, "", -time.Hour))
+	if err != nil || request.Provider != provider.Name || request.State != c.QueryParam("state") {
+		return a.renderLoginError(c, "Sign in request is expired, please try again")
+	}
+
+	if errorCode := c.QueryParam("error"); errorCode != "" {
+		c.Logger().Warnf("Sign in with '%s' failed: %s %s", provider.Name, errorCode, c.QueryParam("error_description"))
+		return a.renderLoginError(c, fmt.Sprintf("Failed to sign in with %s", provider.Title))
+	}
+
+	token, err := provider.Exchange(c.Request().Context(), c.QueryParam("code"), oidcRedirectUrl(c, provider), &oidc.Authorization{
+		State:    request.State,
+		Nonce:    request.Nonce,
+		Verifier: request.Verifier,
+	})
+	if err != nil {
+		c.Logger().Errorf("Failed to sign in with '%s': %s", provider.Name, err)
+		return a.renderLoginError(c, fmt.Sprintf("Failed to sign in with %s", provider.Title))
+	}
+
+	currentUserId := linkingUserId(c, handler)
+	identity, err := a.identities.FindIdentity(provider.Name, token.Subject)
+	if err != nil && err != listing.ErrNotFound {
+		c.Logger().Errorf("Failed to find identity: %s", err)
+		return echo.ErrInternalServerError
+	}
+
+	// Link identity to the current user
+	if currentUserId != "" {
+		if identity != nil && identity.UserId() != currentUserId {
+			return a.renderLoginError(c, fmt.Sprintf("This %s account is already linked to another user", provider.Title))
+		}
+		if identity == nil {
+			if err := a.addIdentity(provider, token, currentUserId); err != nil {
+				c.Logger().Errorf("Failed to link identity: %s", err)
+				return echo.ErrInternalServerError
+			}
+		}
+		return c.Redirect(http.StatusSeeOther, "/account")
+	}
+
+	var user listing.User
+	if identity != nil {
+		user, err = a.users.GetUserById(identity.UserId())
+		if err != nil {
+			c.Logger().Errorf("Failed to get user '%s': %s", identity.UserId(), err)
+			return echo.ErrInternalServerError
+		}
+	} else if provider.AutoProvision {
+		user, err = a.provisionUser(c, provider, token)
+		if err != nil {
+			c.Logger().Errorf("Failed to create user for '%s' identity: %s", provider.Name, err)
+			return a.renderLoginError(c, "Failed to create user account, please try again")
+		}
+	} else {
+		return a.renderLoginError(c, fmt.Sprintf("No account is linked to this %s account. Log in and link it from your account page.", provider.Title))
+	}
+
+	if err := handler.SignInWithoutPassword(c, user); err != nil {
+		c.Logger().Errorf("Failed to sign in with user '%s': %s", user.Id(), err)
+		return a.renderLoginError(c, "Failed to sign in to the account, please try again")
+	}
+
+	return c.Redirect(http.StatusSeeOther, "/feeds")
+}
+
+func readOIDCRequest(c echo.Context) (*oidcRequest, error) {
+	cookie, err := c.Cookie(oidcCookieName)
+	if err != nil {
+		return nil, err
+	}
+	value, err := base64.RawURLEncoding.DecodeString(cookie.Value)
+	if err != nil {
+		return nil, err
+	}
+	var request oidcRequest
+	if err := json.Unmarshal(value, &request); err != nil {
+		return nil, err
+	}
+	return &request, nil
+}
+
+func (a *App) addIdentity(provider *oidc.Provider, token *oidc.IDToken, userId string) error {
+	return a.identities.AddIdentity(adding.IdentityData{
+		Provider: provider.Name,
+		Subject:  token.Subject,
+		UserId:   userId,
+		Email:    token.Email,
+	})
+}
+
+// provisionUser creates user for the identity, users created this way don't
+// have passwords
+func (a *App) provisionUser(c echo.Context, provider *oidc.Provider, token *oidc.IDToken) (listing.User, error) {
+	username, err := a.availableUsername(token)
+	if err != nil {
+		return nil, err
+	}
+
+	created, err := a.users.AddUser(adding.UserData{
+		Email:         token.Email,
+		Username:      username,
+		EmailVerified: token.Email != "" && token.EmailVerified,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := a.addIdentity(provider, token, created.Id()); err != nil {
+		return nil, err
+	}
+
+	user, err := a.users.GetUserById(created.Id())
+	if err != nil {
+		return nil, err
+	}
+	if err := a.createFirstFeed(user); err != nil {
+		c.Logger().Errorf("Failed to create first feed for user '%s': %s", user.Id(), err)
+	}
+	return user, nil
+}
+
+// availableUsername picks an unused username based on the identity claims
+func (a *App) availableUsername(token *oidc.IDToken) (string, error) {
+	base := token.PreferredUsername
+	if base == "" {
+		base = strings.SplitN(token.Email, "@", 2)[0]
+	}
+	base = strings.Trim(usernameReplacer.ReplaceAllString(base, "-"), "-")
+	if base == "" {
+		base = "user"
+	}
+
+	for i := 1; i <= 100; i++ {
+		username := base
+		if i > 1 {
+			username = fmt.Sprintf("%s-%v", base, i)
+		}
+		if _, err := a.users.FindUserByUsername(username); err != nil {
+			return username, nil
+		}
+	}
+	return "", fmt.Errorf("no username is available for '%s'", base)
+}
+
+func (a *App) renderLoginError(c echo.Context, message string) error {
+	return c.Render(http.StatusOK, "login.html", echo.Map{
+		"Error": message,
+		"Title": "Login",
+	})
+}
+
+// POST /account/identities/remove
+func (a *App) postIdentityRemoveHandler(c echo.Context) error {
+	userId, err := GetUserId(c)
+	if err != nil {
+		c.Logger().Errorf("Failed to get user id: %s", err)
+		return echo.ErrInternalServerError
+	}
+
+	user, err := a.users.GetUserById(userId)
+	if err != nil {
+		c.Logger().Errorf("Failed to get user '%s': %s", userId, err)
+		return echo.ErrInternalServerError
+	}
+	identities, err := a.identities.GetUserIdentities(userId)
+	if err != nil {
+		c.Logger().Errorf("Failed to get identities: %s", err)
+		return echo.ErrInternalServerError
+	}
+
+	// Users without password would be locked out
+	if user.PasswordHash() == "" && len(identities) <= 1 {
+		return echo.NewHTTPError(http.StatusBadRequest, "The only sign in method of the account can't be removed")
+	}
+
+	if err := a.identities.RemoveUserIdentity(userId, c.FormValue("provider"), c.FormValue("subject")); err != nil {
+		c.Logger().Errorf("Failed to remove identity: %s", err)
+		return echo.ErrInternalServerError
+	}
+
+	return c.Redirect(http.StatusSeeOther, "/account")
+}
@@ -0,0 +1,79 @@
+package web
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/themisir/myfeed/pkg/oidc"
+)
+
+// recordingRenderer records the last rendered template
+type recordingRenderer struct {
+	name string
+	data echo.Map
+}
+
+func (r *recordingRenderer) Render(w io.Writer, name string, data interface{}, c echo.Context) error {
+	r.name = name
+	r.data, _ = data.(echo.Map)
+	return nil
+}
+
+func oidcRequestCookie(request oidcRequest) *http.Cookie {
+	value, _ := json.Marshal(request)
+	return &http.Cookie{Name: oidcCookieName, Value: base64.RawURLEncoding.EncodeToString(value)}
+}
+
+func TestOIDCCallbackChecksState(t *testing.T) {
+	a := &App{providers: map[string]*oidc.Provider{
+		"mock": oidc.NewProvider(oidc.Config{Name: "mock", Title: "Mock"}),
+	}}
+
+	const expired = "Sign in request is expired, please try again"
+	tests := []struct {
+		name   string
+		cookie *http.Cookie
+		want   string
+	}{
+		{"missing cookie", nil, expired},
+		{"malformed cookie", &http.Cookie{Name: oidcCookieName, Value: "%%%"}, expired},
+		{"state mismatch", oidcRequestCookie(oidcRequest{Provider: "mock", State: "other"}), expired},
+		{"provider mismatch", oidcRequestCookie(oidcRequest{Provider: "other", State: "state"}), expired},
+		// Matching request reaches the error returned by the provider
+		{"matching state", oidcRequestCookie(oidcRequest{Provider: "mock", State: "state"}), "Failed to sign in with Mock"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			e := echo.New()
+			r := &recordingRenderer{}
+			e.Renderer = r
+
+			req := httptest.NewRequest(http.MethodGet, "/login/oidc/mock/callback?state=state&error=access_denied", nil)
+			if test.cookie != nil {
+				req.AddCookie(test.cookie)
+			}
+			rec := httptest.NewRecorder()
+			c := e.NewContext(req, rec)
+			c.SetParamNames("provider")
+			c.SetParamValues("mock")
+
+			if err := a.getOIDCCallbackHandler(c, nil); err != nil {
+				t.Fatalf("callback failed: %s", err)
+			}
+			if r.name != "login.html" || r.data["Error"] != test.want {
+				t.Errorf("rendered %s with error %q, want %q", r.name, r.data["Error"], test.want)
+			}
+
+			// Request cookie is cleared, so it can't be replayed
+			if cookie := rec.Header().Get(echo.HeaderSetCookie); !strings.HasPrefix(cookie, oidcCookieName+"=;") {
+				t.Errorf("request cookie is not cleared: %q", cookie)
+			}
+		})
+	}
+}
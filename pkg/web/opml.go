@@ -0,0 +1,301 @@
+package web
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/themisir/myfeed/pkg/adding"
+	"github.com/themisir/myfeed/pkg/listing"
+	"github.com/themisir/myfeed/pkg/opml"
+	"github.com/themisir/myfeed/pkg/sources"
+)
+
+// Maximum size of the uploaded OPML documents
+const maxOpmlSize = 5 << 20
+
+// GET /feeds/opml
+func (a *App) getFeedsOpmlHandler(c echo.Context) error {
+	userId, err := GetUserId(c)
+	if err != nil {
+		c.Logger().Errorf("Failed to get user id: %s", err)
+		return echo.ErrInternalServerError
+	}
+
+	feeds, err := a.feeds.GetUserFeeds(userId)
+	if err != nil {
+		c.Logger().Errorf("Failed to fetch feeds: %s", err)
+		return echo.ErrInternalServerError
+	}
+
+	doc := opml.New("myfeed subscriptions", time.Now())
+	for _, feed := range feeds {
+		folder, err := a.feedOutline(feed)
+		if err != nil {
+			c.Logger().Errorf("Failed to list feed '%v' sources: %s", feed.Id(), err)
+			return echo.ErrInternalServerError
+		}
+		doc.Outlines = append(doc.Outlines, folder)
+	}
+
+	return writeOpml(c, doc, "myfeed.opml")
+}
+
+// GET /feeds/:feedId/opml
+func (a *App) getFeedOpmlHandler(c echo.Context) error {
+	// Parse feed id
+	feedId, err := strconv.Atoi(c.Param("feedId"))
+	if err != nil {
+		return echo.ErrNotFound
+	}
+
+	// Find feed
+	feed, err := a.feeds.GetFeed(feedId)
+	if err != nil {
+		return echo.ErrNotFound
+	}
+
+	// Check access
+	if !feed.IsPublic() {
+		userId, err := GetUserId(c)
+		if err != nil || userId != feed.UserId() {
+			return echo.ErrForbidden
+		}
+	}
+
+	folder, err := a.feedOutline(feed)
+	if err != nil {
+		c.Logger().Errorf("Failed to list feed '%v' sources: %s", feedId, err)
+		return echo.ErrInternalServerError
+	}
+
+	doc := opml.New(feed.Name(), time.Now())
+	doc.Outlines = []opml.Outline{folder}
+
+	return writeOpml(c, doc, fmt.Sprintf("feed-%v.opml", feedId))
+}
+
+// feedOutline maps feed into a folder containing its sources
+func (a *App) feedOutline(feed listing.Feed) (opml.Outline, error) {
+	feedSources, err := a.sources.GetFeedSources(feed.Id())
+	if err != nil {
+		return opml.Outline{}, err
+	}
+
+	outlines := make([]opml.Outline, len(feedSources))
+	for i, source := range feedSources {
+		title := source.CustomTitle()
+		if title == "" {
+			title = source.Title()
+		}
+		outlines[i] = opml.Subscription(title, source.Url(), source.SiteUrl())
+	}
+
+	return opml.Folder(feed.Name(), outlines...), nil
+}
+
+func writeOpml(c echo.Context, doc *opml.Document, filename string) error {
+	body := new(bytes.Buffer)
+	if err := doc.Write(body); err != nil {
+		c.Logger().Errorf("Failed to write opml: %s", err)
+		return echo.ErrInternalServerError
+	}
+
+	c.Response().Header().Set(echo.HeaderContentDisposition, fmt.Sprintf(`attachment; filename="%s"`, filename))
+	return c.Blob(http.StatusOK, "text/x-opml; charset=utf-8", body.Bytes())
+}
+
+// GET /feeds/import
+func (a *App) getFeedsImportHandler(c echo.Context) error {
+	return c.Render(http.StatusOK, "feeds/import.html", echo.Map{
+		"Title": "Import feeds",
+	})
+}
+
+// importedFeed reports outcome of importing a single OPML folder
+type importedFeed struct {
+	// Id of the created feed, zero when nothing is added
+	Id   int
+	Name string
+
+	Added      []opml.Outline
+	Duplicates []opml.Outline
+	Invalid    []invalidOutline
+}
+
+type invalidOutline struct {
+	Outline opml.Outline
+	Reason  string
+}
+
+// POST /feeds/import
+func (a *App) postFeedsImportHandler(c echo.Context) error {
+	userId, err := GetUserId(c)
+	if err != nil {
+		c.Logger().Errorf("Failed to get user id: %s", err)
+		return echo.ErrInternalServerError
+	}
+
+	renderError := func(message string) error {
+		return c.Render(http.StatusOK, "feeds/import.html", echo.Map{
+			"Title": "Import feeds",
+			"Error": message,
+		})
+	}
+
+	// Parse uploaded document
+	header, err := c.FormFile("file")
+	if err != nil {
+		return renderError("Please select an OPML file")
+	}
+	if header.Size > maxOpmlSize {
+		return renderError("The file is too large")
+	}
+	file, err := header.Open()
+	if err != nil {
+		c.Logger().Errorf("Failed to open uploaded file: %s", err)
+		return echo.ErrInternalServerError
+	}
+	defer file.Close()
+
+	doc, err := opml.Parse(file)
+	if err != nil {
+		return renderError("The file is not a valid OPML document")
+	}
+
+	// Folders are imported as feeds, subscriptions outside of folders are
+	// collected into a single feed
+	var folders []opml.Outline
+	var loose []opml.Outline
+	for _, outline := range doc.Outlines {
+		if outline.IsSubscription() {
+			// Nested subscriptions are collected from the folder
+			loose = append(loose, outline)
+		} else {
+			folders = append(folders, outline)
+		}
+	}
+	if len(loose) > 0 {
+		name := doc.Head.Title
+		if name == "" {
+			name = "Imported feed"
+		}
+		folders = append(folders, opml.Folder(name, loose...))
+	}
+
+	// Sources the user already subscribed to are reported as duplicates,
+	// so importing the same file again doesn't create the feeds again
+	existing, err := a.userSourceUrls(userId)
+	if err != nil {
+		c.Logger().Errorf("Failed to get sources of user '%s': %s", userId, err)
+		return echo.ErrInternalServerError
+	}
+
+	report := make([]importedFeed, 0, len(folders))
+	for _, folder := range folders {
+		imported, err := a.importFeed(userId, folder, existing)
+		if err != nil {
+			c.Logger().Errorf("Failed to import feed '%s': %s", folder.Name(), err)
+			return echo.ErrInternalServerError
+		}
+		report = append(report, imported)
+	}
+
+	return c.Render(http.StatusOK, "feeds/import.html", echo.Map{
+		"Title":  "Import feeds",
+		"Report": report,
+	})
+}
+
+// userSourceUrls returns urls of the sources in the user feeds
+func (a *App) userSourceUrls(userId string) (map[string]bool, error) {
+	feeds, err := a.feeds.GetUserFeeds(userId)
+	if err != nil {
+		return nil, err
+	}
+
+	urls := make(map[string]bool)
+	for _, feed := range feeds {
+		feedSources, err := a.sources.GetFeedSources(feed.Id())
+		if err != nil {
+			return nil, err
+		}
+		for _, source := range feedSources {
+			urls[source.Url()] = true
+		}
+	}
+	return urls, nil
+}
+
+// importFeed creates a feed containing subscriptions of the folder, feed is
+// not created when folder contains no valid subscriptions. Subscriptions to
+// the existing sources are skipped.
+func (a *App) importFeed(userId string, folder opml.Outline, existing map[string]bool) (importedFeed, error) {
+	result := importedFeed{Name: strings.TrimSpace(folder.Name())}
+	if result.Name == "" {
+		result.Name = "Imported feed"
+	}
+
+	var feedSources []sources.FeedSource
+	seen := make(map[string]bool)
+	for _, outline := range folder.Subscriptions() {
+		sourceUrl, err := url.Parse(strings.TrimSpace(outline.XmlUrl))
+		switch {
+		case err != nil:
+			result.Invalid = append(result.Invalid, invalidOutline{outline, "invalid url"})
+			continue
+		case sourceUrl.Scheme != "http" && sourceUrl.Scheme != "https":
+			result.Invalid = append(result.Invalid, invalidOutline{outline, "unsupported url scheme"})
+			continue
+		case sourceUrl.Host == "":
+			result.Invalid = append(result.Invalid, invalidOutline{outline, "missing host"})
+			continue
+		case sources.IsPrivateHost(sourceUrl.Hostname()):
+			result.Invalid = append(result.Invalid, invalidOutline{outline, "private network address"})
+			continue
+		}
+
+		if seen[sourceUrl.String()] || existing[sourceUrl.String()] {
+			result.Duplicates = append(result.Duplicates, outline)
+			continue
+		}
+		seen[sourceUrl.String()] = true
+
+		// Outline names usually repeat the source title, keep them as
+		// custom titles only when they're renamed, so the titles follow
+		// the source otherwise
+		var title string
+		if source, _ := a.sources.FindSourceByUrl(sourceUrl.String()); source != nil {
+			if name := strings.TrimSpace(outline.Name()); name != source.Title() {
+				title = name
+			}
+		}
+
+		result.Added = append(result.Added, outline)
+		feedSources = append(feedSources, sources.FeedSource{
+			Url:   sourceUrl.String(),
+			Title: title,
+		})
+	}
+
+	if len(feedSources) == 0 {
+		return result, nil
+	}
+
+	feed, err := a.feeds.AddFeed(adding.FeedData{
+		Name:     result.Name,
+		UserId:   userId,
+		IsPublic: false,
+	})
+	if err != nil {
+		return result, err
+	}
+	result.Id = feed.Id()
+
+	return result, a.sourceManager.UpdateFeedSources(feed.Id(), feedSources...)
+}
@@ -0,0 +1,81 @@
+package web
+
+import (
+	"github.com/labstack/echo/v4"
+	"github.com/themisir/myfeed/pkg/listing"
+)
+
+// Number of posts displayed on a single page
+const postsPerPage = 30
+
+// pageNav contains cursors of the adjacent pages, nil when there's no such
+// page
+type pageNav struct {
+	Older *listing.Cursor
+	Newer *listing.Cursor
+}
+
+// parsePage reads page cursor from "before" or "after" query params. One
+// more post than limit is requested for detecting whether there are more
+// pages.
+func parsePage(c echo.Context, limit int) (listing.Page, error) {
+	page := listing.Page{Limit: limit + 1}
+	if value := c.QueryParam("before"); value != "" {
+		cursor, err := listing.ParseCursor(value)
+		if err != nil {
+			return page, err
+		}
+		page.Before = &cursor
+	} else if value := c.QueryParam("after"); value != "" {
+		cursor, err := listing.ParseCursor(value)
+		if err != nil {
+			return page, err
+		}
+		page.After = &cursor
+	}
+	return page, nil
+}
+
+// pageRange returns range of the fetched posts to display and cursors of the
+// adjacent pages given cursors of the posts fetched using parsePage. Listing
+// continues from the scanned cursor when it's not nil.
+func pageRange(page listing.Page, cursors []listing.Cursor, limit int, scanned *listing.Cursor) (start int, end int, nav pageNav) {
+	start, end = 0, len(cursors)
+	more := len(cursors) > limit
+
+	if page.After != nil {
+		// Extra post is the newest one
+		if more {
+			start++
+		}
+		if start < end {
+			nav.Older = &cursors[end-1]
+			if more {
+				nav.Newer = &cursors[start]
+			}
+		} else {
+			nav.Older = page.After
+		}
+		if scanned != nil {
+			nav.Newer = scanned
+		}
+		return
+	}
+
+	// Extra post is the oldest one
+	if more {
+		end = limit
+		nav.Older = &cursors[end-1]
+	}
+	if page.Before != nil {
+		if start < end {
+			nav.Newer = &cursors[start]
+		} else {
+			nav.Newer = page.Before
+		}
+	}
+	if scanned != nil {
+		nav.Older = scanned
+	}
+	return
+}
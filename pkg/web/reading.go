@@ -0,0 +1,104 @@
+package web
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/themisir/myfeed/pkg/listing"
+)
+
+// Ages accepted by the "mark older than" form, empty value marks every post
+var readAges = map[string]time.Duration{
+	"":    0,
+	"1d":  24 * time.Hour,
+	"7d":  7 * 24 * time.Hour,
+	"30d": 30 * 24 * time.Hour,
+}
+
+// unreadCounts returns number of unread posts in the user feeds, nil when
+// user isn't logged in
+func (a *App) unreadCounts(c echo.Context) map[int]int {
+	userId, err := GetUserId(c)
+	if err != nil || userId == "" {
+		return nil
+	}
+	counts, err := a.posts.GetUnreadCounts(userId)
+	if err != nil {
+		c.Logger().Errorf("Failed to get unread counts: %s", err)
+		return nil
+	}
+	return counts
+}
+
+// POST /feeds/:feedId/read
+func (a *App) postFeedReadHandler(c echo.Context) error {
+	feedId, err := strconv.Atoi(c.Param("feedId"))
+	if err != nil {
+		return echo.ErrNotFound
+	}
+
+	userId, err := GetUserId(c)
+	if err != nil {
+		c.Logger().Errorf("Failed to get user id: %s", err)
+		return echo.ErrInternalServerError
+	}
+
+	feed, err := a.feeds.GetFeed(feedId)
+	if err != nil {
+		return echo.ErrNotFound
+	}
+	if !feed.IsPublic() && feed.UserId() != userId {
+		return echo.ErrForbidden
+	}
+
+	age, ok := readAges[c.FormValue("older_than")]
+	if !ok {
+		return echo.ErrBadRequest
+	}
+	var before *time.Time
+	if age > 0 {
+		t := time.Now().UTC().Add(-age)
+		before = &t
+	}
+
+	if err := a.posts.MarkFeedRead(userId, feedId, before); err != nil {
+		c.Logger().Errorf("Failed to mark feed '%v' as read: %s", feedId, err)
+		return echo.ErrInternalServerError
+	}
+
+	redirectUrl := fmt.Sprintf("/feeds/%v", feedId)
+	if c.FormValue("unread") != "" {
+		redirectUrl += "?unread=1"
+	}
+	return c.Redirect(http.StatusSeeOther, redirectUrl)
+}
+
+// POST /posts/:postId/read
+// POST /posts/:postId/unread
+func (a *App) postPostReadHandler(read bool) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		postId, err := strconv.Atoi(c.Param("postId"))
+		if err != nil {
+			return echo.ErrNotFound
+		}
+
+		userId, err := GetUserId(c)
+		if err != nil {
+			c.Logger().Errorf("Failed to get user id: %s", err)
+			return echo.ErrInternalServerError
+		}
+
+		if err := a.posts.MarkPostRead(userId, postId, read); err != nil {
+			if err == listing.ErrNotFound {
+				return echo.ErrNotFound
+			}
+			c.Logger().Errorf("Failed to update post '%v' read state: %s", postId, err)
+			return echo.ErrInternalServerError
+		}
+
+		return c.NoContent(http.StatusNoContent)
+	}
+}
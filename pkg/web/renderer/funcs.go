@@ -0,0 +1,34 @@
+package renderer
+
+import (
+	"html/template"
+	"net/url"
+	"strings"
+)
+
+// SafeFuncs returns template helpers for rendering untrusted content:
+//
+//	safeURL  - keeps http(s) and mailto urls and replaces others with "#"
+//	safeHTML - sanitizes html using given function and renders it as is
+func SafeFuncs(sanitize func(string) string) template.FuncMap {
+	return template.FuncMap{
+		"safeURL": safeURL,
+		"safeHTML": func(s string) template.HTML {
+			return template.HTML(sanitize(s))
+		},
+	}
+}
+
+func safeURL(s string) template.URL {
+	parsed, err := url.Parse(strings.TrimSpace(s))
+	if err != nil {
+		return "#"
+	}
+
+	switch parsed.Scheme {
+	case "http", "https", "mailto":
+		return template.URL(parsed.String())
+	default:
+		return "#"
+	}
+}
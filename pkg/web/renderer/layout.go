@@ -2,6 +2,7 @@ package renderer
 
 import (
 	"bytes"
+	"html/template"
 	"io"
 
 	"github.com/labstack/echo/v4"
@@ -20,9 +21,10 @@ type layoutedRenderer struct {
 	layout string
 }
 
-// Data struct passed to layout tempaltes
+// Data struct passed to layout tempaltes, Inner is already escaped by the
+// inner template
 type layoutData struct {
-	Inner string
+	Inner template.HTML
 	Data  interface{}
 }
 
@@ -36,5 +38,5 @@ func (t *layoutedRenderer) Render(w io.Writer, name string, data interface{}, c
 	}
 
 	// Render outer template
-	return t.base.Render(w, t.layout, layoutData{Inner: inner.String(), Data: data}, c)
+	return t.base.Render(w, t.layout, layoutData{Inner: template.HTML(inner.String()), Data: data}, c)
 }
@@ -0,0 +1,167 @@
+package renderer
+
+import (
+	"html/template"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/themisir/myfeed/pkg/sources"
+	"github.com/themisir/myfeed/static"
+)
+
+const hostileScript = `<script>alert(1)</script>`
+const hostileImage = `<img src="x" onerror="alert(1)">`
+
+type testFeed struct{}
+
+func (testFeed) Id() int      { return 1 }
+func (testFeed) Name() string { return hostileScript }
+
+type testSource struct {
+	Url, SiteUrl, Title string
+}
+
+type testPost struct {
+	Id          int
+	Read, Saved bool
+	Url         string
+	Title       string
+	Author      string
+	Description string
+	PublishedAt *time.Time
+	Source      *testSource
+}
+
+func newTestRenderer() echo.Renderer {
+	funcs := SafeFuncs(func(s string) string {
+		return sources.SanitizeHTML(s, nil)
+	})
+	return Layout("layout.html", Template(static.FS, "views", funcs))
+}
+
+func newTestContext() echo.Context {
+	return echo.New().NewContext(httptest.NewRequest("GET", "/", nil), httptest.NewRecorder())
+}
+
+func render(t *testing.T, r echo.Renderer, name string, data echo.Map) string {
+	t.Helper()
+	out := new(strings.Builder)
+	if err := r.Render(out, name, data, newTestContext()); err != nil {
+		t.Fatalf("failed to render %s: %s", name, err)
+	}
+	return out.String()
+}
+
+func assertNoLiveTags(t *testing.T, out string) {
+	t.Helper()
+	for _, live := range []string{hostileScript, hostileImage, `onerror="`, "javascript:"} {
+		if strings.Contains(out, live) {
+			t.Errorf("output contains %q", live)
+		}
+	}
+}
+
+func TestSingleFeedEscapesPosts(t *testing.T) {
+	out := render(t, newTestRenderer(), "feeds/single.html", echo.Map{
+		"Title": hostileScript,
+		"Feed":  testFeed{},
+		"Posts": []testPost{{
+			Id:          1,
+			Url:         "javascript:alert(1)",
+			Title:       hostileScript + hostileImage,
+			Author:      hostileImage,
+			Description: "<p>text</p>" + hostileScript + hostileImage,
+			Source:      &testSource{Url: "https://example.com/feed", SiteUrl: "JavaScript:alert(1)", Title: hostileScript},
+		}},
+	})
+
+	assertNoLiveTags(t, out)
+
+	// Plain text fields are escaped
+	for _, escaped := range []string{
+		`<title>&lt;script&gt;alert(1)&lt;/script&gt; - Myfeed</title>`,
+		`<h2>&lt;script&gt;alert(1)&lt;/script&gt;</h2>`,
+		`&lt;img src=&#34;x&#34; onerror=&#34;alert(1)&#34;&gt;`,
+	} {
+		if !strings.Contains(out, escaped) {
+			t.Errorf("output doesn't contain %q", escaped)
+		}
+	}
+
+	// Description keeps allowed markup only
+	if !strings.Contains(out, `<div class="post-description"><p>text</p><img></div>`) {
+		t.Errorf("sanitized description is missing")
+	}
+
+	// Unsafe urls are replaced
+	if !strings.Contains(out, `<a class="post-link" href="#">`) || !strings.Contains(out, `<a class="post-source" href="#"`) {
+		t.Errorf("unsafe post urls are not replaced")
+	}
+}
+
+func TestSingleFeedEscapesDecodedTitles(t *testing.T) {
+	// Titles are stored as plain text with their entities decoded
+	title := sources.PlainText("&lt;script&gt;alert(1)&lt;/script&gt;")
+	out := render(t, newTestRenderer(), "feeds/single.html", echo.Map{
+		"Feed":  testFeed{},
+		"Posts": []testPost{{Id: 1, Url: "https://example.com/1", Title: title}},
+	})
+
+	assertNoLiveTags(t, out)
+	if !strings.Contains(out, `<a class="post-link" href="https://example.com/1">&lt;script&gt;alert(1)&lt;/script&gt;</a>`) {
+		t.Errorf("decoded title is not escaped")
+	}
+}
+
+func TestLayoutDoesNotEscapeInnerTwice(t *testing.T) {
+	out := render(t, newTestRenderer(), "feeds/single.html", echo.Map{"Feed": testFeed{}})
+
+	if strings.Count(out, "<h2>&lt;script&gt;alert(1)&lt;/script&gt;</h2>") != 1 {
+		t.Errorf("inner template is not rendered escaped exactly once")
+	}
+	if strings.Contains(out, "&amp;lt;") || strings.Contains(out, "&lt;h2&gt;") {
+		t.Errorf("inner template is escaped by the layout")
+	}
+}
+
+func TestLayoutRendersInnerAsHTML(t *testing.T) {
+	fsys := fstest.MapFS{
+		"views/layout.html": {Data: []byte(`<title>{{ .Data.Title }}</title>{{ .Inner }}`)},
+		"views/page.html":   {Data: []byte(`<p>{{ .Title }}</p>`)},
+	}
+	r := Layout("layout.html", Template(fsys, "views", nil))
+
+	out := render(t, r, "page.html", echo.Map{"Title": hostileScript})
+	escaped := "&lt;script&gt;alert(1)&lt;/script&gt;"
+	if want := "<title>" + escaped + "</title><p>" + escaped + "</p>"; out != want {
+		t.Errorf("got %q, want %q", out, want)
+	}
+}
+
+func TestSafeURL(t *testing.T) {
+	tests := []struct {
+		url  string
+		want template.URL
+	}{
+		{"https://example.com/a?b=c", "https://example.com/a?b=c"},
+		{"http://example.com", "http://example.com"},
+		{"mailto:user@example.com", "mailto:user@example.com"},
+		{"  https://example.com  ", "https://example.com"},
+		{"javascript:alert(1)", "#"},
+		{"JavaScript:alert(1)", "#"},
+		{" javascript:alert(1)", "#"},
+		{"java\tscript:alert(1)", "#"},
+		{"data:text/html,<script>alert(1)</script>", "#"},
+		{"vbscript:msgbox(1)", "#"},
+		{"/relative", "#"},
+	}
+	for _, test := range tests {
+		if got := safeURL(test.url); got != test.want {
+			t.Errorf("safeURL(%q) = %q, want %q", test.url, got, test.want)
+		}
+	}
+}
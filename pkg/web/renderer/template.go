@@ -1,14 +1,14 @@
 package renderer
 
 import (
+	"html/template"
 	"io"
 	"io/fs"
-	"text/template"
 
 	"github.com/labstack/echo/v4"
 )
 
-func Template(fsys fs.FS, root string) echo.Renderer {
+func Template(fsys fs.FS, root string, funcs template.FuncMap) echo.Renderer {
 	var tmpl *template.Template = nil
 
 	if err := fs.WalkDir(fsys, root, func(path string, d fs.DirEntry, err error) error {
@@ -27,7 +27,7 @@ func Template(fsys fs.FS, root string) echo.Renderer {
 			}
 
 			if tmpl == nil {
-				tmpl = template.Must(template.New(name).Parse(string(bytes)))
+				tmpl = template.Must(template.New(name).Funcs(funcs).Parse(string(bytes)))
 			} else {
 				tmpl = template.Must(tmpl.New(name).Parse(string(bytes)))
 			}
@@ -0,0 +1,134 @@
+package web
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+	"github.com/themisir/myfeed/pkg/filtering"
+	"github.com/themisir/myfeed/pkg/listing"
+)
+
+// Number of recent posts rules are previewed on
+const previewLimit = 50
+
+// ruleRow is a single row of the rule editor
+type ruleRow struct {
+	filtering.Rule
+
+	// Choices of the rule editor
+	Actions  []string
+	Fields   []string
+	Matchers []string
+}
+
+func newRuleRow(rule filtering.Rule) ruleRow {
+	return ruleRow{
+		Rule:     rule,
+		Actions:  filtering.Actions,
+		Fields:   filtering.Fields,
+		Matchers: filtering.Matchers,
+	}
+}
+
+func ruleRows(rules []filtering.Rule) []ruleRow {
+	rows := make([]ruleRow, len(rules))
+	for i, rule := range rules {
+		rows[i] = newRuleRow(rule)
+	}
+	return rows
+}
+
+// rulesForm contains rules submitted using the rule editor
+type rulesForm struct {
+	Actions  []string `form:"rule_action"`
+	Fields   []string `form:"rule_field"`
+	Matchers []string `form:"rule_matcher"`
+	Patterns []string `form:"rule_pattern"`
+}
+
+// rules returns submitted rules, rules with empty patterns are ignored
+func (f *rulesForm) rules() []filtering.Rule {
+	var result []filtering.Rule
+	for i, pattern := range f.Patterns {
+		pattern = strings.TrimSpace(pattern)
+		if pattern == "" || i >= len(f.Actions) || i >= len(f.Fields) || i >= len(f.Matchers) {
+			continue
+		}
+		result = append(result, filtering.Rule{
+			Action:  f.Actions[i],
+			Field:   f.Fields[i],
+			Matcher: f.Matchers[i],
+			Pattern: pattern,
+		})
+	}
+	return result
+}
+
+// previewPost is a recent post of the feed with the rule hiding it
+type previewPost struct {
+	Title  string `json:"title"`
+	Url    string `json:"url"`
+	Source string `json:"source"`
+	Hidden bool   `json:"hidden"`
+	// Rule is the index of the exclude rule hiding the post, -1 when the post
+	// doesn't match any of the include rules
+	Rule int `json:"rule"`
+}
+
+// POST /feeds/:feedId/rules/preview
+func (a *App) postFeedRulesPreviewHandler(c echo.Context) error {
+	userId, err := GetUserId(c)
+	if err != nil {
+		c.Logger().Errorf("Failed to get user id: %s", err)
+		return echo.ErrInternalServerError
+	}
+
+	// Parse feed id
+	feedId, err := strconv.Atoi(c.Param("feedId"))
+	if err != nil {
+		return echo.ErrNotFound
+	}
+
+	// Find feed
+	feed, err := a.feeds.GetFeed(feedId)
+	if err != nil {
+		return echo.ErrNotFound
+	}
+	if feed.UserId() != userId {
+		return echo.ErrForbidden
+	}
+
+	// Bind request body
+	body := new(rulesForm)
+	if err := c.Bind(body); err != nil {
+		c.Logger().Errorf("Failed to bind body: %s", err)
+		return echo.ErrBadRequest
+	}
+
+	filter, err := filtering.Compile(body.rules())
+	if err != nil {
+		return c.JSON(http.StatusOK, echo.Map{"error": err.Error()})
+	}
+
+	posts, _, err := a.unfilteredPosts.GetFeedPosts(feedId, listing.Page{Limit: previewLimit})
+	if err != nil {
+		c.Logger().Errorf("Failed to get feed '%v' posts: %s", feedId, err)
+		return echo.ErrInternalServerError
+	}
+
+	result := make([]previewPost, len(posts))
+	for i, post := range posts {
+		result[i] = previewPost{
+			Title: post.Title(),
+			Url:   post.Url(),
+		}
+		if source := post.Source(); source != nil {
+			result[i].Source = source.Title()
+		}
+		result[i].Rule, result[i].Hidden = filter.Hides(post)
+	}
+
+	return c.JSON(http.StatusOK, echo.Map{"posts": result})
+}
@@ -0,0 +1,147 @@
+package web
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/labstack/echo/v4"
+	"github.com/themisir/myfeed/pkg/listing"
+	"github.com/themisir/myfeed/pkg/syndication"
+)
+
+var savedAlternates = []alternate{
+	{Title: "RSS", Type: syndication.RSS.ContentType, Url: "/saved.rss"},
+	{Title: "JSON Feed", Type: syndication.JSON.ContentType, Url: "/saved.json"},
+}
+
+// GET /saved
+func (a *App) getSavedHandler(c echo.Context) error {
+	userId, err := GetUserId(c)
+	if err != nil {
+		c.Logger().Errorf("Failed to get user id: %s", err)
+		return echo.ErrInternalServerError
+	}
+
+	page, err := parsePage(c, postsPerPage)
+	if err != nil {
+		return echo.ErrBadRequest
+	}
+	items, err := a.saved.GetSavedItems(userId, page)
+	if err != nil {
+		c.Logger().Errorf("Failed to get saved items: %s", err)
+		return echo.ErrInternalServerError
+	}
+
+	cursors := make([]listing.Cursor, len(items))
+	for i, item := range items {
+		cursors[i] = item.Cursor()
+	}
+	start, end, nav := pageRange(page, cursors, postsPerPage, nil)
+
+	return c.Render(http.StatusOK, "saved.html", echo.Map{
+		"Items":      items[start:end],
+		"Page":       nav,
+		"Title":      "Saved",
+		"Alternates": savedAlternates,
+	})
+}
+
+// GET /saved.rss, /saved.json
+func (a *App) getSavedExportHandler(format *syndication.Format) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		userId, err := GetUserId(c)
+		if err != nil {
+			c.Logger().Errorf("Failed to get user id: %s", err)
+			return echo.ErrInternalServerError
+		}
+
+		// Export contains every saved item
+		items, err := a.saved.GetSavedItems(userId, listing.Page{})
+		if err != nil {
+			c.Logger().Errorf("Failed to get saved items: %s", err)
+			return echo.ErrInternalServerError
+		}
+
+		doc := &syndication.Feed{
+			Title:   "Saved items",
+			Link:    absoluteUrl(c, "/saved"),
+			FeedUrl: absoluteUrl(c, c.Request().URL.Path),
+			Items:   make([]syndication.Item, len(items)),
+		}
+		for i, item := range items {
+			savedAt := item.SavedAt()
+			doc.Items[i] = syndication.Item{
+				Id:        item.Url(),
+				Title:     item.Title(),
+				Link:      item.Url(),
+				Content:   item.Description(),
+				Published: item.PublishedAt(),
+				Updated:   &savedAt,
+			}
+			if item.SourceUrl() != "" {
+				doc.Items[i].Source = &syndication.Source{
+					Title:   item.SourceTitle(),
+					FeedUrl: item.SourceUrl(),
+					SiteUrl: item.SiteUrl(),
+				}
+			}
+		}
+
+		// Saved items are personal, don't let shared caches store them
+		c.Response().Header().Set("Cache-Control", "private, max-age=300")
+		return writeSyndication(c, format, doc)
+	}
+}
+
+// POST /posts/:postId/save
+// POST /posts/:postId/unsave
+func (a *App) postPostSaveHandler(save bool) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		postId, err := strconv.Atoi(c.Param("postId"))
+		if err != nil {
+			return echo.ErrNotFound
+		}
+
+		userId, err := GetUserId(c)
+		if err != nil {
+			c.Logger().Errorf("Failed to get user id: %s", err)
+			return echo.ErrInternalServerError
+		}
+
+		if save {
+			err = a.saved.SavePost(userId, postId)
+		} else {
+			err = a.saved.RemoveSavedPost(userId, postId)
+		}
+		if err == listing.ErrNotFound {
+			return echo.ErrNotFound
+		}
+		if err != nil {
+			c.Logger().Errorf("Failed to update post '%v' saved state: %s", postId, err)
+			return echo.ErrInternalServerError
+		}
+
+		return c.NoContent(http.StatusNoContent)
+	}
+}
+
+// POST /saved/:itemId/remove
+func (a *App) postSavedRemoveHandler(c echo.Context) error {
+	itemId, err := strconv.Atoi(c.Param("itemId"))
+	if err != nil {
+		return echo.ErrNotFound
+	}
+
+	userId, err := GetUserId(c)
+	if err != nil {
+		c.Logger().Errorf("Failed to get user id: %s", err)
+		return echo.ErrInternalServerError
+	}
+
+	if err := a.saved.RemoveSavedItem(userId, itemId); err != nil {
+		c.Logger().Errorf("Failed to remove saved item '%v': %s", itemId, err)
+		return echo.ErrInternalServerError
+	}
+
+	return c.Redirect(http.StatusSeeOther, "/saved")
+}
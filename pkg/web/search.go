@@ -0,0 +1,128 @@
+package web
+
+import (
+	"html"
+	"html/template"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+	"github.com/themisir/myfeed/pkg/listing"
+)
+
+// Number of search results displayed on a single page
+const searchResultsPerPage = 20
+
+// searchResult is a search result with highlighted headlines
+type searchResult struct {
+	listing.SearchResult
+	Title       template.HTML
+	Description template.HTML
+}
+
+// highlight escapes search headline and replaces highlight delimiters with
+// mark elements
+func highlight(headline string) template.HTML {
+	escaped := html.EscapeString(headline)
+	escaped = strings.ReplaceAll(escaped, listing.HighlightStart, "<mark>")
+	escaped = strings.ReplaceAll(escaped, listing.HighlightStop, "</mark>")
+	return template.HTML(escaped)
+}
+
+// GET /search
+func (a *App) getSearchHandler(c echo.Context) error {
+	userId, _ := GetUserId(c)
+	search := listing.Search{
+		Query: strings.TrimSpace(c.QueryParam("q")),
+		Scope: c.QueryParam("scope"),
+		Limit: searchResultsPerPage + 1,
+	}
+
+	// Parse page number
+	page := 1
+	if value := c.QueryParam("page"); value != "" {
+		var err error
+		if page, err = strconv.Atoi(value); err != nil || page < 1 {
+			return echo.ErrBadRequest
+		}
+	}
+	search.Offset = (page - 1) * searchResultsPerPage
+
+	// Resolve scope, user feeds are searched by default when logged in
+	var feed listing.Feed
+	if value := c.QueryParam("feed"); value != "" {
+		feedId, err := strconv.Atoi(value)
+		if err != nil {
+			return echo.ErrNotFound
+		}
+		if feed, err = a.feeds.GetFeed(feedId); err != nil {
+			return echo.ErrNotFound
+		}
+		if !feed.IsPublic() && feed.UserId() != userId {
+			return echo.ErrForbidden
+		}
+		if search.Scope == "" {
+			search.Scope = listing.SearchFeed
+		}
+		search.FeedId = feedId
+	}
+	if search.Scope == "" {
+		if userId != "" {
+			search.Scope = listing.SearchUser
+		} else {
+			search.Scope = listing.SearchPublic
+		}
+	}
+
+	switch search.Scope {
+	case listing.SearchFeed:
+		if feed == nil {
+			return echo.ErrBadRequest
+		}
+	case listing.SearchUser:
+		if userId == "" {
+			return c.Redirect(http.StatusSeeOther, "/login")
+		}
+		search.UserId = userId
+	case listing.SearchPublic:
+	default:
+		return echo.ErrBadRequest
+	}
+
+	data := echo.Map{
+		"Title": "Search",
+		"Query": search.Query,
+		"Scope": search.Scope,
+		"Feed":  feed,
+	}
+
+	if search.Query != "" {
+		posts, err := a.posts.SearchPosts(search)
+		if err != nil {
+			c.Logger().Errorf("Failed to search posts: %s", err)
+			return echo.ErrInternalServerError
+		}
+
+		if len(posts) > searchResultsPerPage {
+			posts = posts[:searchResultsPerPage]
+			data["NextPage"] = page + 1
+		}
+		if page > 1 {
+			data["PrevPage"] = page - 1
+		}
+
+		results := make([]searchResult, len(posts))
+		for i, post := range posts {
+			results[i] = searchResult{
+				SearchResult: post,
+				Title:        highlight(post.TitleHeadline()),
+				Description:  highlight(post.DescriptionHeadline()),
+			}
+		}
+		data["Results"] = results
+		data["Title"] = search.Query + " - Search"
+	}
+
+	return c.Render(http.StatusOK, "search.html", data)
+}
@@ -0,0 +1,146 @@
+package web
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/themisir/myfeed/pkg/listing"
+	"github.com/themisir/myfeed/pkg/syndication"
+)
+
+// Maximum number of posts included in syndication feeds
+const syndicationLimit = 50
+
+// alternate is a link to the page in another format
+type alternate struct {
+	Title string
+	Type  string
+	Url   string
+}
+
+// splitFormat splits syndication format extension from the route param, nil
+// format is returned when param has no known extension
+func splitFormat(param string) (string, *syndication.Format) {
+	i := strings.LastIndexByte(param, '.')
+	if i < 0 {
+		return param, nil
+	}
+	format, ok := syndication.Formats[param[i+1:]]
+	if !ok {
+		return param, nil
+	}
+	return param[:i], format
+}
+
+// absoluteUrl returns absolute url of the given path on the requested host
+func absoluteUrl(c echo.Context, path string) string {
+	return fmt.Sprintf("%s://%s%s", c.Scheme(), c.Request().Host, path)
+}
+
+// postTagUri returns a tag URI identifying the post, see RFC 4151. Unlike
+// post urls it doesn't change when the post is moved or the url is shared by
+// multiple posts.
+func postTagUri(c echo.Context, post listing.Post) string {
+	host := c.Request().Host
+	if name, _, err := net.SplitHostPort(host); err == nil {
+		host = name
+	}
+	return fmt.Sprintf("tag:%s,2022:posts/%v", host, post.Id())
+}
+
+func feedAlternates(feed listing.Feed) []alternate {
+	return []alternate{
+		{Title: "RSS", Type: syndication.RSS.ContentType, Url: fmt.Sprintf("/feeds/%v.rss", feed.Id())},
+		{Title: "Atom", Type: syndication.Atom.ContentType, Url: fmt.Sprintf("/feeds/%v.atom", feed.Id())},
+		{Title: "JSON Feed", Type: syndication.JSON.ContentType, Url: fmt.Sprintf("/feeds/%v.json", feed.Id())},
+	}
+}
+
+// feedDocument maps feed posts into a syndication feed
+func feedDocument(c echo.Context, feed listing.Feed, posts []listing.SourcePost, nav pageNav) *syndication.Feed {
+	doc := &syndication.Feed{
+		Title:   feed.Name(),
+		Link:    absoluteUrl(c, fmt.Sprintf("/feeds/%v", feed.Id())),
+		FeedUrl: absoluteUrl(c, c.Request().URL.Path),
+		Items:   make([]syndication.Item, len(posts)),
+	}
+	if nav.Older != nil {
+		doc.NextUrl = absoluteUrl(c, c.Request().URL.Path+"?before="+nav.Older.String())
+	}
+
+	for i, post := range posts {
+		doc.Items[i] = syndication.Item{
+			Id:        postTagUri(c, post),
+			Title:     post.Title(),
+			Link:      post.Url(),
+			Content:   post.Description(),
+			Published: post.PublishedAt(),
+			Updated:   post.UpdatedAt(),
+		}
+		if source := post.Source(); source != nil {
+			doc.Items[i].Source = &syndication.Source{
+				Title:   source.Title(),
+				FeedUrl: source.Url(),
+				SiteUrl: source.SiteUrl(),
+			}
+		}
+	}
+
+	return doc
+}
+
+// writeSyndication serializes the feed in the given format, responding with
+// 304 Not Modified when client already has the same document. Responses are
+// publicly cacheable unless Cache-Control header is already set.
+func writeSyndication(c echo.Context, format *syndication.Format, feed *syndication.Feed) error {
+	body := new(bytes.Buffer)
+	if err := format.Write(body, feed); err != nil {
+		c.Logger().Errorf("Failed to write feed: %s", err)
+		return echo.ErrInternalServerError
+	}
+
+	hash := sha1.Sum(body.Bytes())
+	etag := `"` + hex.EncodeToString(hash[:]) + `"`
+	lastModified := feed.LastModified()
+
+	header := c.Response().Header()
+	header.Set("ETag", etag)
+	if header.Get("Cache-Control") == "" {
+		header.Set("Cache-Control", "public, max-age=300")
+	}
+	if !lastModified.IsZero() {
+		header.Set("Last-Modified", lastModified.UTC().Format(http.TimeFormat))
+	}
+
+	if notModified(c.Request(), etag, lastModified) {
+		return c.NoContent(http.StatusNotModified)
+	}
+
+	return c.Blob(http.StatusOK, format.ContentType, body.Bytes())
+}
+
+// notModified evaluates conditional request headers, If-Modified-Since is
+// ignored when If-None-Match is present
+func notModified(req *http.Request, etag string, lastModified time.Time) bool {
+	if match := req.Header.Get("If-None-Match"); match != "" {
+		for _, candidate := range strings.Split(match, ",") {
+			candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+			if candidate == etag || candidate == "*" {
+				return true
+			}
+		}
+		return false
+	}
+
+	if since, err := http.ParseTime(req.Header.Get("If-Modified-Since")); err == nil && !lastModified.IsZero() {
+		return !lastModified.Truncate(time.Second).After(since)
+	}
+	return false
+}
@@ -0,0 +1,41 @@
+package web
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/themisir/myfeed/pkg/listing"
+)
+
+type testPost struct {
+	listing.Post
+	id  int
+	url string
+}
+
+func (p testPost) Id() int     { return p.id }
+func (p testPost) Url() string { return p.url }
+
+func TestPostTagUri(t *testing.T) {
+	tests := []struct {
+		host string
+		post testPost
+		want string
+	}{
+		{"example.com", testPost{id: 1, url: "https://blog.example.org/post"}, "tag:example.com,2022:posts/1"},
+		// Posts sharing the url have different ids, ports aren't allowed in tag
+		// authorities
+		{"example.com:8080", testPost{id: 2, url: "https://blog.example.org/post"}, "tag:example.com,2022:posts/2"},
+	}
+	for _, test := range tests {
+		req := httptest.NewRequest(http.MethodGet, "/feeds/1.atom", nil)
+		req.Host = test.host
+		c := echo.New().NewContext(req, httptest.NewRecorder())
+
+		if got := postTagUri(c, test.post); got != test.want {
+			t.Errorf("got %s, want %s", got, test.want)
+		}
+	}
+}
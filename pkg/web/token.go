@@ -0,0 +1,80 @@
+package web
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+	"github.com/themisir/myfeed/pkg/adding"
+	"github.com/themisir/myfeed/pkg/auth"
+)
+
+// Maximum length of the token names
+const maxTokenNameLength = 100
+
+// Scopes granted to tokens by the scope field of the create form
+var tokenScopes = map[string][]string{
+	"read":  {auth.ScopeRead},
+	"write": {auth.ScopeRead, auth.ScopeFeedsWrite},
+}
+
+// POST /account/tokens
+// Creates a personal access token, the token is displayed only once since
+// only its hash is stored
+func (a *App) postTokenCreateHandler(c echo.Context) error {
+	userId, err := GetUserId(c)
+	if err != nil {
+		c.Logger().Errorf("Failed to get user id: %s", err)
+		return echo.ErrInternalServerError
+	}
+
+	name := strings.TrimSpace(c.FormValue("name"))
+	if name == "" || len(name) > maxTokenNameLength {
+		return a.renderAccount(c, echo.Map{"TokenError": "Token name must be between 1 and 100 characters long"})
+	}
+	scopes, ok := tokenScopes[c.FormValue("scope")]
+	if !ok {
+		return a.renderAccount(c, echo.Map{"TokenError": "Invalid token scope"})
+	}
+
+	token, hash, err := auth.NewToken()
+	if err != nil {
+		c.Logger().Errorf("Failed to generate token: %s", err)
+		return echo.ErrInternalServerError
+	}
+
+	err = a.tokens.AddToken(adding.TokenData{
+		UserId: userId,
+		Name:   name,
+		Hash:   hash,
+		Scopes: scopes,
+	})
+	if err != nil {
+		c.Logger().Errorf("Failed to create token: %s", err)
+		return echo.ErrInternalServerError
+	}
+
+	return a.renderAccount(c, echo.Map{"NewToken": token})
+}
+
+// POST /account/tokens/:tokenId/revoke
+func (a *App) postTokenRevokeHandler(c echo.Context) error {
+	userId, err := GetUserId(c)
+	if err != nil {
+		c.Logger().Errorf("Failed to get user id: %s", err)
+		return echo.ErrInternalServerError
+	}
+
+	tokenId, err := strconv.Atoi(c.Param("tokenId"))
+	if err != nil {
+		return echo.ErrNotFound
+	}
+
+	if err := a.tokens.RemoveUserToken(userId, tokenId); err != nil {
+		c.Logger().Errorf("Failed to revoke token: %s", err)
+		return echo.ErrInternalServerError
+	}
+
+	return c.Redirect(http.StatusSeeOther, "/account")
+}
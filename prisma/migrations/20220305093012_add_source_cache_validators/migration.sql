@@ -0,0 +1,3 @@
+-- AlterTable
+ALTER TABLE "sources" ADD COLUMN     "etag" TEXT NOT NULL DEFAULT '',
+ADD COLUMN     "last_modified" TEXT NOT NULL DEFAULT '';
@@ -0,0 +1,13 @@
+-- AlterTable
+ALTER TABLE "posts" ADD COLUMN     "guid" TEXT;
+
+-- Existing posts were keyed by their links
+UPDATE "posts" SET "guid" = "url";
+
+DELETE FROM "posts" a USING "posts" b
+WHERE a."source_id" = b."source_id" AND a."guid" = b."guid" AND a."id" < b."id";
+
+ALTER TABLE "posts" ALTER COLUMN "guid" SET NOT NULL;
+
+-- CreateIndex
+CREATE UNIQUE INDEX "posts_source_id_guid_key" ON "posts"("source_id", "guid");
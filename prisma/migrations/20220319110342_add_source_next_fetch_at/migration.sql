@@ -0,0 +1,5 @@
+-- AlterTable
+ALTER TABLE "sources" ADD COLUMN     "next_fetch_at" TIMESTAMP(3) NOT NULL DEFAULT CURRENT_TIMESTAMP;
+
+-- CreateIndex
+CREATE INDEX "sources_next_fetch_at_idx" ON "sources"("next_fetch_at");
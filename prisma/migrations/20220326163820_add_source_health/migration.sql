@@ -0,0 +1,5 @@
+-- AlterTable
+ALTER TABLE "sources" ADD COLUMN     "last_success_at" TIMESTAMP(3),
+ADD COLUMN     "last_error" TEXT NOT NULL DEFAULT '',
+ADD COLUMN     "last_status" INTEGER NOT NULL DEFAULT 0,
+ADD COLUMN     "failure_count" INTEGER NOT NULL DEFAULT 0;
@@ -0,0 +1,7 @@
+-- AlterTable
+ALTER TABLE "sources" ADD COLUMN     "description" TEXT NOT NULL DEFAULT '',
+ADD COLUMN     "site_url" TEXT NOT NULL DEFAULT '',
+ADD COLUMN     "image_url" TEXT NOT NULL DEFAULT '';
+
+-- AlterTable
+ALTER TABLE "feed_source" ADD COLUMN     "title" TEXT NOT NULL DEFAULT '';
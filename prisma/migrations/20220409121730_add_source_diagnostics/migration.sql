@@ -0,0 +1,2 @@
+-- AlterTable
+ALTER TABLE "sources" ADD COLUMN     "diagnostics" TEXT NOT NULL DEFAULT '';
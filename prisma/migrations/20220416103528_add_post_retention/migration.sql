@@ -0,0 +1,6 @@
+-- AlterTable
+ALTER TABLE "feeds" ADD COLUMN     "retention_days" INTEGER,
+ADD COLUMN     "retention_posts" INTEGER;
+
+-- AlterTable
+ALTER TABLE "sources" ADD COLUMN     "item_count" INTEGER NOT NULL DEFAULT 0;
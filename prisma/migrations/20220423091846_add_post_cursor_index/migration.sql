@@ -0,0 +1,5 @@
+-- CreateIndex
+-- Expression indexes can't be declared in the prisma schema. Source posts are
+-- listed using keyset pagination on this key, feed listings span multiple
+-- sources and aren't covered by this index.
+CREATE INDEX "posts_source_id_sorted_at_id_idx" ON "posts"("source_id", COALESCE("published_at", "created_at") DESC, "id" DESC);
@@ -0,0 +1,23 @@
+-- AlterTable
+ALTER TABLE "posts" ADD COLUMN     "author" TEXT NOT NULL DEFAULT '',
+ADD COLUMN     "categories" TEXT[] DEFAULT ARRAY[]::TEXT[];
+
+-- CreateTable
+CREATE TABLE "feed_rules" (
+    "id" SERIAL NOT NULL,
+    "feed_id" INTEGER NOT NULL,
+    "position" INTEGER NOT NULL,
+    "action" TEXT NOT NULL,
+    "field" TEXT NOT NULL,
+    "matcher" TEXT NOT NULL,
+    "pattern" TEXT NOT NULL,
+    "created_at" TIMESTAMP(3) NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+    CONSTRAINT "feed_rules_pkey" PRIMARY KEY ("id")
+);
+
+-- CreateIndex
+CREATE INDEX "feed_rules_feed_id_position_idx" ON "feed_rules"("feed_id", "position");
+
+-- AddForeignKey
+ALTER TABLE "feed_rules" ADD CONSTRAINT "feed_rules_feed_id_fkey" FOREIGN KEY ("feed_id") REFERENCES "feeds"("id") ON DELETE CASCADE ON UPDATE CASCADE;
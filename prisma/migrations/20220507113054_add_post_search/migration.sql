@@ -0,0 +1,8 @@
+-- AlterTable
+ALTER TABLE "posts" ADD COLUMN     "search" tsvector GENERATED ALWAYS AS (
+    setweight(to_tsvector('english', coalesce("title", '')), 'A') ||
+    setweight(to_tsvector('english', coalesce("description", '')), 'B')
+) STORED;
+
+-- CreateIndex
+CREATE INDEX "posts_search_idx" ON "posts" USING GIN ("search");
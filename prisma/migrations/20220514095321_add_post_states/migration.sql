@@ -0,0 +1,18 @@
+-- CreateTable
+CREATE TABLE "post_states" (
+    "user_id" TEXT NOT NULL,
+    "post_id" INTEGER NOT NULL,
+    "is_read" BOOLEAN NOT NULL DEFAULT false,
+    "updated_at" TIMESTAMP(3) NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+    CONSTRAINT "post_states_pkey" PRIMARY KEY ("user_id","post_id")
+);
+
+-- CreateIndex
+CREATE INDEX "post_states_post_id_idx" ON "post_states"("post_id");
+
+-- AddForeignKey
+ALTER TABLE "post_states" ADD CONSTRAINT "post_states_user_id_fkey" FOREIGN KEY ("user_id") REFERENCES "users"("id") ON DELETE CASCADE ON UPDATE CASCADE;
+
+-- AddForeignKey
+ALTER TABLE "post_states" ADD CONSTRAINT "post_states_post_id_fkey" FOREIGN KEY ("post_id") REFERENCES "posts"("id") ON DELETE CASCADE ON UPDATE CASCADE;
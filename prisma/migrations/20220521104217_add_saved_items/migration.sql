@@ -0,0 +1,29 @@
+-- CreateTable
+CREATE TABLE "saved_items" (
+    "id" SERIAL NOT NULL,
+    "user_id" TEXT NOT NULL,
+    "post_id" INTEGER,
+    "title" TEXT NOT NULL,
+    "description" TEXT NOT NULL,
+    "url" TEXT NOT NULL,
+    "author" TEXT NOT NULL DEFAULT '',
+    "source_title" TEXT NOT NULL DEFAULT '',
+    "source_url" TEXT NOT NULL DEFAULT '',
+    "site_url" TEXT NOT NULL DEFAULT '',
+    "published_at" TIMESTAMP(3),
+    "saved_at" TIMESTAMP(3) NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+    CONSTRAINT "saved_items_pkey" PRIMARY KEY ("id")
+);
+
+-- CreateIndex
+CREATE UNIQUE INDEX "saved_items_user_id_post_id_key" ON "saved_items"("user_id", "post_id");
+
+-- CreateIndex
+CREATE INDEX "saved_items_user_id_saved_at_idx" ON "saved_items"("user_id", "saved_at");
+
+-- AddForeignKey
+ALTER TABLE "saved_items" ADD CONSTRAINT "saved_items_user_id_fkey" FOREIGN KEY ("user_id") REFERENCES "users"("id") ON DELETE CASCADE ON UPDATE CASCADE;
+
+-- AddForeignKey
+ALTER TABLE "saved_items" ADD CONSTRAINT "saved_items_post_id_fkey" FOREIGN KEY ("post_id") REFERENCES "posts"("id") ON DELETE SET NULL ON UPDATE CASCADE;